@@ -0,0 +1,157 @@
+package metricsexporter
+/**
+ * Security audit of Compute Engine instances.
+ *
+ * @see https://cloud.google.com/compute/docs/instances/access-overview
+ * @see https://cloud.google.com/security-command-center/docs/concepts-vulnerabilities-findings#compute-findings
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+const (
+    CLOUD_PLATFORM_SCOPE = "https://www.googleapis.com/auth/cloud-platform"
+    DEFAULT_COMPUTE_SA   = "-compute@developer.gserviceaccount.com"
+)
+
+/* Security findings of an instance.
+ */
+type instanceAudit struct {
+    Name      string    `json:"name"`
+    Zone      string    `json:"zone"`
+    Findings  []string  `json:"findings"`
+}
+
+/* Security findings of the instances.
+ */
+type instancesAuditReport struct {
+    Instances  []instanceAudit  `json:"instances"`
+    Counts     map[string]int   `json:"counts"`
+}
+
+/* getInstancesAudit audits the instances of the zone, or of every zone if
+ * the zone is "-". OS Login and the serial port are set in the instance
+ * metadata, else in the project metadata.
+ */
+func (n *Compute) getInstancesAudit() (string, error) {
+    proj, err := n.client.Projects.Get(n.Project).Do()
+    if err != nil {
+        return fmt.Sprintf("failed to get project: "), err
+    }
+
+    var instances []*compute.Instance
+    if n.Zone == "" || n.Zone == "-" {
+        err = n.client.Instances.AggregatedList(n.Project).Pages(n.context, func(list *compute.InstanceAggregatedList) error {
+            for _, scoped := range list.Items {
+                instances = append(instances, scoped.Instances...)
+            }
+            return nil
+        })
+    } else {
+        err = n.client.Instances.List(n.Project, n.Zone).Pages(n.context, func(list *compute.InstanceList) error {
+            instances = append(instances, list.Items...)
+            return nil
+        })
+    }
+    if err != nil {
+        return fmt.Sprintf("failed to list instances: "), err
+    }
+
+    res := instancesAuditReport{
+        Instances: []instanceAudit{},
+        Counts:    map[string]int{},
+    }
+    for _, i := range instances {
+        a := newInstanceAudit(i, proj.CommonInstanceMetadata)
+        for _, f := range a.Findings {
+            res.Counts[f]++
+        }
+        res.Instances = append(res.Instances, a)
+    }
+
+    if n.EnableEmitter {
+        findings := newGaugeVec("gcp_instance_audit_findings", "Number of instances with the security finding.", "project", "finding")
+        for f, count := range res.Counts {
+            findings.WithLabelValues(n.Project, f).Set(float64(count))
+        }
+        zone := n.Zone
+        if zone == "" {
+            zone = "-"
+        }
+        if err := emitCollectors(n.emitter, "compute.instances.audit." + zone, n.Project, findings); err != nil {
+            return fmt.Sprintf("failed to emit instance audit metrics: "), err
+        }
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newInstanceAudit runs every check on instance i. projectMetadata holds
+ * the project-wide defaults of the metadata keys.
+ */
+func newInstanceAudit(i *compute.Instance, projectMetadata *compute.Metadata) instanceAudit {
+    res := instanceAudit{
+        Name:     i.Name,
+        Zone:     lastPathElement(i.Zone),
+        Findings: []string{},
+    }
+    metadata := func(key string) string {
+        if v := instanceMetadata(i, key); v != "" {
+            return strings.ToLower(v)
+        }
+        return strings.ToLower(metadataValue(projectMetadata, key))
+    }
+
+    for _, nic := range i.NetworkInterfaces {
+        if len(nic.AccessConfigs) > 0 {
+            res.Findings = append(res.Findings, "external_ip")
+            break
+        }
+    }
+
+    for _, sa := range i.ServiceAccounts {
+        if !strings.HasSuffix(sa.Email, DEFAULT_COMPUTE_SA) {
+            continue
+        }
+        for _, scope := range sa.Scopes {
+            if scope == CLOUD_PLATFORM_SCOPE {
+                res.Findings = append(res.Findings, "default_service_account_full_access")
+            }
+        }
+    }
+
+    shielded := i.ShieldedInstanceConfig
+    if shielded == nil || !shielded.EnableSecureBoot {
+        res.Findings = append(res.Findings, "secure_boot_disabled")
+    }
+    if shielded == nil || !shielded.EnableVtpm {
+        res.Findings = append(res.Findings, "vtpm_disabled")
+    }
+    if shielded == nil || !shielded.EnableIntegrityMonitoring {
+        res.Findings = append(res.Findings, "integrity_monitoring_disabled")
+    }
+
+    if v := metadata("enable-oslogin"); v != "true" && v != "1" {
+        res.Findings = append(res.Findings, "os_login_disabled")
+    }
+    if v := metadata("serial-port-enable"); v == "true" || v == "1" {
+        res.Findings = append(res.Findings, "serial_port_enabled")
+    }
+
+    if i.CanIpForward {
+        res.Findings = append(res.Findings, "ip_forwarding")
+    }
+
+    sort.Strings(res.Findings)
+    return res
+}
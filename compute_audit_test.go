@@ -0,0 +1,92 @@
+package metricsexporter
+
+import (
+    "strings"
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestNewInstanceAudit(t *testing.T) {
+    value := func(v string) *string {
+        return &v
+    }
+    metadata := func(kv ...string) *compute.Metadata {
+        m := &compute.Metadata{}
+        for i := 0; i + 1 < len(kv); i += 2 {
+            m.Items = append(m.Items, &compute.MetadataItems{Key: kv[i], Value: value(kv[i + 1])})
+        }
+        return m
+    }
+    shielded := &compute.ShieldedInstanceConfig{EnableSecureBoot: true, EnableVtpm: true, EnableIntegrityMonitoring: true}
+
+    tests := []struct {
+        name      string
+        instance  *compute.Instance
+        project   *compute.Metadata
+        want      string
+    }{
+        {
+            "hardened",
+            &compute.Instance{ShieldedInstanceConfig: shielded, Metadata: metadata("enable-oslogin", "TRUE")},
+            nil,
+            "",
+        },
+        {
+            "defaults",
+            &compute.Instance{},
+            nil,
+            "integrity_monitoring_disabled,os_login_disabled,secure_boot_disabled,vtpm_disabled",
+        },
+        {
+            "project metadata",
+            &compute.Instance{ShieldedInstanceConfig: shielded},
+            metadata("enable-oslogin", "1", "serial-port-enable", "true"),
+            "serial_port_enabled",
+        },
+        {
+            "instance metadata overrides the project",
+            &compute.Instance{ShieldedInstanceConfig: shielded, Metadata: metadata("enable-oslogin", "false")},
+            metadata("enable-oslogin", "true"),
+            "os_login_disabled",
+        },
+        {
+            "exposed",
+            &compute.Instance{
+                ShieldedInstanceConfig: shielded,
+                Metadata:               metadata("enable-oslogin", "true"),
+                CanIpForward:           true,
+                NetworkInterfaces:      []*compute.NetworkInterface{{}, {AccessConfigs: []*compute.AccessConfig{{NatIP: "203.0.113.1"}}}},
+                ServiceAccounts: []*compute.ServiceAccount{
+                    {Email: "123-compute@developer.gserviceaccount.com", Scopes: []string{CLOUD_PLATFORM_SCOPE}},
+                },
+            },
+            nil,
+            "default_service_account_full_access,external_ip,ip_forwarding",
+        },
+        {
+            "custom service account with full access",
+            &compute.Instance{
+                ShieldedInstanceConfig: shielded,
+                Metadata:               metadata("enable-oslogin", "true"),
+                ServiceAccounts: []*compute.ServiceAccount{
+                    {Email: "app@p.iam.gserviceaccount.com", Scopes: []string{CLOUD_PLATFORM_SCOPE}},
+                    {Email: "123-compute@developer.gserviceaccount.com", Scopes: []string{"https://www.googleapis.com/auth/devstorage.read_only"}},
+                },
+            },
+            nil,
+            "",
+        },
+    }
+    for _, tt := range tests {
+        tt.instance.Name = "vm"
+        tt.instance.Zone = COMPUTE_API_PREFIX + "projects/p/zones/us-central1-a"
+        got := newInstanceAudit(tt.instance, tt.project)
+        if findings := strings.Join(got.Findings, ","); findings != tt.want {
+            t.Errorf("%s: findings = %s, want %s", tt.name, findings, tt.want)
+        }
+        if got.Name != "vm" || got.Zone != "us-central1-a" || got.Findings == nil {
+            t.Errorf("%s: newInstanceAudit() = %+v", tt.name, got)
+        }
+    }
+}
@@ -0,0 +1,301 @@
+package metricsexporter
+/**
+ * Cost estimation of the inventory (instances, GKE node pools, disks and
+ * external addresses) from the local price catalog.
+ *
+ * Running instances are assumed to run all month: their hourly and monthly
+ * costs get the full sustained use discount of their family. Preemptible
+ * and spot instances get the preemptible discount instead.
+ *
+ * @see https://cloud.google.com/compute/all-pricing
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+const (
+    COST_NO_LABEL = "none"
+)
+
+/* Estimated cost of a resource.
+ */
+type costItem struct {
+    Project      string   `json:"project"`
+    Type         string   `json:"type"`
+    Name         string   `json:"name"`
+    Location     string   `json:"location"`
+    Label        string   `json:"label"`
+    NodePool     string   `json:"nodePool,omitempty"`
+    HourlyCost   float64  `json:"hourlyCost"`
+    MonthlyCost  float64  `json:"monthlyCost"`
+}
+
+/* Total estimated cost.
+ */
+type costTotal struct {
+    HourlyCost   float64  `json:"hourlyCost"`
+    MonthlyCost  float64  `json:"monthlyCost"`
+}
+
+/* Cost estimate and its breakdowns.
+ */
+type costEstimate struct {
+    LabelKey   string                `json:"labelKey,omitempty"`
+    Items      []costItem            `json:"items"`
+    ByProject  map[string]costTotal  `json:"byProject"`
+    ByType     map[string]costTotal  `json:"byType"`
+    ByLabel    map[string]costTotal  `json:"byLabel,omitempty"`
+    Total      costTotal             `json:"total"`
+}
+
+/* getCostEstimate estimates the hourly and monthly cost of the project, and
+ * of the other projects in Arg1 (comma separated). Set the label key to
+ * also break the cost down by the value of that label.
+ */
+func (n *Compute) getCostEstimate(projects, labelKey string) (string, error) {
+    prices, err := loadPriceTable()
+    if err != nil {
+        return fmt.Sprintf("failed to load price catalog: "), err
+    }
+
+    res := costEstimate{
+        LabelKey:  labelKey,
+        Items:     []costItem{},
+        ByProject: map[string]costTotal{},
+        ByType:    map[string]costTotal{},
+    }
+    if labelKey != "" {
+        res.ByLabel = map[string]costTotal{}
+    }
+
+    list := []string{n.Project}
+    for _, p := range strings.Split(projects, ",") {
+        if p = strings.TrimSpace(p); p != "" && p != n.Project {
+            list = append(list, p)
+        }
+    }
+    for _, project := range list {
+        items, err := n.estimateProjectCost(project, labelKey, prices)
+        if err != nil {
+            return fmt.Sprintf("failed to estimate the cost of project %s: ", project), err
+        }
+        res.Items = append(res.Items, items...)
+    }
+
+    add := func(totals map[string]costTotal, key string, item costItem) {
+        t := totals[key]
+        t.HourlyCost += item.HourlyCost
+        t.MonthlyCost += item.MonthlyCost
+        totals[key] = t
+    }
+    for _, item := range res.Items {
+        add(res.ByProject, item.Project, item)
+        add(res.ByType, item.Type, item)
+        if labelKey != "" {
+            add(res.ByLabel, item.Label, item)
+        }
+        res.Total.HourlyCost += item.HourlyCost
+        res.Total.MonthlyCost += item.MonthlyCost
+    }
+
+    if err := n.emitCostEstimate(res); err != nil {
+        return fmt.Sprintf("failed to emit cost metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* estimateProjectCost estimates the cost of every running instance, disk,
+ * external static address and ephemeral external address of project.
+ * Instances of GKE nodes are reported as node pools.
+ */
+func (n *Compute) estimateProjectCost(project, labelKey string, prices priceTable) ([]costItem, error) {
+    var res []costItem
+    label := func(labels map[string]string) string {
+        if v, ok := labels[labelKey]; ok && v != "" {
+            return v
+        }
+        return COST_NO_LABEL
+    }
+
+    shapes, err := n.listMachineShapes(project)
+    if err != nil {
+        return nil, err
+    }
+
+    // External addresses of the running instances, priced once we know
+    // which ones are static addresses.
+    var natIps []string
+    var instanceIps []costItem
+
+    err = n.client.Instances.AggregatedList(project).Pages(n.context, func(list *compute.InstanceAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, i := range scoped.Instances {
+                if i.Status != "RUNNING" {
+                    continue
+                }
+                zone := lastPathElement(i.Zone)
+                machineType := lastPathElement(i.MachineType)
+                hourly, sud := prices.instanceHourlyCost(machineType, machineTypeShape(shapes, zone, machineType))
+                hourly = hourly * prices.regionMultiplier(zone)
+                if i.Scheduling != nil && i.Scheduling.Preemptible {
+                    hourly = hourly * (1 - prices.PreemptibleDiscount)
+                } else {
+                    hourly = hourly * (1 - sud)
+                }
+                item := costItem{
+                    Project:     project,
+                    Type:        "instance",
+                    Name:        i.Name,
+                    Location:    zone,
+                    Label:       label(i.Labels),
+                    HourlyCost:  hourly,
+                    MonthlyCost: hourly * HOURS_PER_MONTH,
+                }
+                if cluster := instanceMetadata(i, "cluster-name"); cluster != "" {
+                    item.Type = "gke_node_pool"
+                    item.NodePool = cluster + "/" + gkeNodePool(instanceMetadata(i, "kube-labels"))
+                }
+                res = append(res, item)
+
+                for _, nic := range i.NetworkInterfaces {
+                    for _, ac := range nic.AccessConfigs {
+                        if ac.NatIP == "" {
+                            continue
+                        }
+                        natIps = append(natIps, ac.NatIP)
+                        hourly := prices.ExternalIpHour * prices.regionMultiplier(zone)
+                        instanceIps = append(instanceIps, costItem{
+                            Project:     project,
+                            Type:        "ephemeral_address",
+                            Name:        i.Name + "/" + ac.NatIP,
+                            Location:    zone,
+                            Label:       item.Label,
+                            HourlyCost:  hourly,
+                            MonthlyCost: hourly * HOURS_PER_MONTH,
+                        })
+                    }
+                }
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    err = n.client.Disks.AggregatedList(project).Pages(n.context, func(list *compute.DiskAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, d := range scoped.Disks {
+                // Regional disks have a region instead of a zone.
+                location := diskLocation(d)
+                monthly := prices.diskMonthlyCost(d) * prices.regionMultiplier(location)
+                res = append(res, costItem{
+                    Project:     project,
+                    Type:        "disk",
+                    Name:        d.Name,
+                    Location:    location,
+                    Label:       label(d.Labels),
+                    HourlyCost:  monthly / HOURS_PER_MONTH,
+                    MonthlyCost: monthly,
+                })
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    // Static external addresses cost more when they are not used.
+    static := map[string]bool{}
+    addressCost := func(a *compute.Address, location string) {
+        if a.AddressType == "INTERNAL" {
+            return
+        }
+        static[a.Address] = true
+        hourly := prices.ExternalIpHour
+        if a.Status == "RESERVED" {
+            hourly = prices.StaticIpMonth / HOURS_PER_MONTH
+        }
+        hourly = hourly * prices.regionMultiplier(location)
+        res = append(res, costItem{
+            Project:     project,
+            Type:        "address",
+            Name:        a.Name,
+            Location:    location,
+            Label:       COST_NO_LABEL,
+            HourlyCost:  hourly,
+            MonthlyCost: hourly * HOURS_PER_MONTH,
+        })
+    }
+    err = n.client.Addresses.AggregatedList(project).Pages(n.context, func(list *compute.AddressAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, a := range scoped.Addresses {
+                addressCost(a, lastPathElement(a.Region))
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    err = n.client.GlobalAddresses.List(project).Pages(n.context, func(list *compute.AddressList) error {
+        for _, a := range list.Items {
+            addressCost(a, SCOPE_GLOBAL)
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+
+    // In use static addresses are already priced with the addresses.
+    for i, item := range instanceIps {
+        if !static[natIps[i]] {
+            res = append(res, item)
+        }
+    }
+    return res, nil
+}
+
+/* gkeNodePool returns the node pool in the kube-labels metadata of a GKE
+ * node, eg- "cloud.google.com/gke-nodepool=default-pool,...".
+ */
+func gkeNodePool(kubeLabels string) string {
+    for _, l := range strings.Split(kubeLabels, ",") {
+        if strings.HasPrefix(l, "cloud.google.com/gke-nodepool=") {
+            return strings.TrimPrefix(l, "cloud.google.com/gke-nodepool=")
+        }
+    }
+    return "unknown"
+}
+
+/* emitCostEstimate sends the estimated cost per project, resource type and
+ * label value as metrics.
+ */
+func (n *Compute) emitCostEstimate(estimate costEstimate) error {
+    if !n.EnableEmitter {
+        return nil
+    }
+
+    labels := []string{"project", "resource_type", "label"}
+    hourly := newGaugeVec("gcp_cost_estimate_hourly_dollars", "Estimated hourly cost of the resources.", labels...)
+    monthly := newGaugeVec("gcp_cost_estimate_monthly_dollars", "Estimated monthly cost of the resources.", labels...)
+
+    for _, item := range estimate.Items {
+        hourly.WithLabelValues(item.Project, item.Type, item.Label).Add(item.HourlyCost)
+        monthly.WithLabelValues(item.Project, item.Type, item.Label).Add(item.MonthlyCost)
+    }
+
+    return emitCollectors(n.emitter, "compute.estimate", n.Project, hourly, monthly)
+}
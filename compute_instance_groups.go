@@ -0,0 +1,196 @@
+package metricsexporter
+/**
+ * Instance groups, and the status of the zonal and regional managed
+ * instance groups (MIGs) with their autoscalers.
+ *
+ * @see https://cloud.google.com/compute/docs/instance-groups
+ * @see https://cloud.google.com/compute/docs/autoscaler
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* Version (instance template) of a managed instance group.
+ */
+type migVersion struct {
+    Name              string  `json:"name,omitempty"`
+    InstanceTemplate  string  `json:"instanceTemplate"`
+    TargetSize        int64   `json:"targetSize"`
+}
+
+/* Autoscaler of a managed instance group.
+ */
+type migAutoscaler struct {
+    Name             string    `json:"name"`
+    Status           string    `json:"status"`
+    RecommendedSize  int64     `json:"recommendedSize"`
+    MinReplicas      int64     `json:"minReplicas"`
+    MaxReplicas      int64     `json:"maxReplicas"`
+    Mode             string    `json:"mode,omitempty"`
+    StatusDetails    []string  `json:"statusDetails,omitempty"`
+}
+
+/* Status of a managed instance group.
+ */
+type migStatus struct {
+    Name                  string            `json:"name"`
+    Location              string            `json:"location"`
+    Regional              bool              `json:"regional"`
+    TargetSize            int64             `json:"targetSize"`
+    CurrentActions        map[string]int64  `json:"currentActions"`
+    IsStable              bool              `json:"isStable"`
+    VersionTargetReached  bool              `json:"versionTargetReached"`
+    Versions              []migVersion      `json:"versions"`
+    Autoscaler            *migAutoscaler    `json:"autoscaler,omitempty"`
+}
+
+/* @see https://cloud.google.com/compute/docs/reference/rest/v1/instanceGroups/aggregatedList
+ */
+func (n *Compute) getInstanceGroupsList() (string, error) {
+    var items []json.Marshaler
+    err := n.client.InstanceGroups.AggregatedList(n.Project).Pages(n.context, func(list *compute.InstanceGroupAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, g := range scoped.InstanceGroups {
+                items = append(items, g)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list instance groups: "), err
+    }
+    return joinIndentJSON(items)
+}
+
+/* getMigsList reports the status of every zonal and regional managed
+ * instance group, with the recommendation of its autoscaler.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/instanceGroupManagers/aggregatedList
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/autoscalers/aggregatedList
+ */
+func (n *Compute) getMigsList() (string, error) {
+    autoscalers := map[string]*compute.Autoscaler{}
+    err := n.client.Autoscalers.AggregatedList(n.Project).Pages(n.context, func(list *compute.AutoscalerAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, a := range scoped.Autoscalers {
+                autoscalers[a.Target] = a
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list autoscalers: "), err
+    }
+
+    res := []migStatus{}
+    err = n.client.InstanceGroupManagers.AggregatedList(n.Project).Pages(n.context, func(list *compute.InstanceGroupManagerAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, m := range scoped.InstanceGroupManagers {
+                res = append(res, newMigStatus(m, autoscalers[m.SelfLink]))
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list instance group managers: "), err
+    }
+
+    if err := n.emitMigs(res); err != nil {
+        return fmt.Sprintf("failed to emit mig metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newMigStatus converts the managed instance group m and its autoscaler a,
+ * which may be nil.
+ */
+func newMigStatus(m *compute.InstanceGroupManager, a *compute.Autoscaler) migStatus {
+    res := migStatus{
+        Name:           m.Name,
+        Location:       lastPathElement(m.Zone),
+        TargetSize:     m.TargetSize,
+        CurrentActions: map[string]int64{},
+        Versions:       []migVersion{},
+    }
+    if m.Region != "" {
+        res.Location = lastPathElement(m.Region)
+        res.Regional = true
+    }
+    if c := m.CurrentActions; c != nil {
+        res.CurrentActions = map[string]int64{
+            "none":                   c.None,
+            "creating":               c.Creating,
+            "creatingWithoutRetries": c.CreatingWithoutRetries,
+            "recreating":             c.Recreating,
+            "deleting":               c.Deleting,
+            "abandoning":             c.Abandoning,
+            "restarting":             c.Restarting,
+            "refreshing":             c.Refreshing,
+            "verifying":              c.Verifying,
+        }
+    }
+    if m.Status != nil {
+        res.IsStable = m.Status.IsStable
+        res.VersionTargetReached = m.Status.VersionTarget != nil && m.Status.VersionTarget.IsReached
+    }
+    for _, v := range m.Versions {
+        mv := migVersion{Name: v.Name, InstanceTemplate: lastPathElement(v.InstanceTemplate)}
+        if v.TargetSize != nil {
+            mv.TargetSize = v.TargetSize.Calculated
+        }
+        res.Versions = append(res.Versions, mv)
+    }
+    if a != nil {
+        ma := &migAutoscaler{
+            Name:            a.Name,
+            Status:          a.Status,
+            RecommendedSize: a.RecommendedSize,
+        }
+        if p := a.AutoscalingPolicy; p != nil {
+            ma.MinReplicas = p.MinNumReplicas
+            ma.MaxReplicas = p.MaxNumReplicas
+            ma.Mode = p.Mode
+        }
+        for _, d := range a.StatusDetails {
+            ma.StatusDetails = append(ma.StatusDetails, d.Message)
+        }
+        res.Autoscaler = ma
+    }
+    return res
+}
+
+/* emitMigs sends the target size, current actions and stability of every
+ * managed instance group as metrics.
+ */
+func (n *Compute) emitMigs(migs []migStatus) error {
+    if !n.EnableEmitter {
+        return nil
+    }
+
+    labels := []string{"project", "location", "mig"}
+    target := newGaugeVec("gcp_mig_target_size", "Target number of instances of the managed instance group.", labels...)
+    instances := newGaugeVec("gcp_mig_instances", "Number of instances of the managed instance group per current action.", append(labels, "action")...)
+    stable := newGaugeVec("gcp_mig_is_stable", "Whether the managed instance group is stable.", labels...)
+    recommended := newGaugeVec("gcp_mig_autoscaler_recommended_size", "Number of instances recommended by the autoscaler.", labels...)
+
+    for _, m := range migs {
+        target.WithLabelValues(n.Project, m.Location, m.Name).Set(float64(m.TargetSize))
+        stable.WithLabelValues(n.Project, m.Location, m.Name).Set(boolToFloat(m.IsStable))
+        for action, count := range m.CurrentActions {
+            instances.WithLabelValues(n.Project, m.Location, m.Name, action).Set(float64(count))
+        }
+        if m.Autoscaler != nil {
+            recommended.WithLabelValues(n.Project, m.Location, m.Name).Set(float64(m.Autoscaler.RecommendedSize))
+        }
+    }
+
+    return emitCollectors(n.emitter, "compute.migs.list", n.Project, target, instances, stable, recommended)
+}
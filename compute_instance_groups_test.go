@@ -0,0 +1,102 @@
+package metricsexporter
+
+import (
+    "reflect"
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestNewMigStatus(t *testing.T) {
+    prefix := COMPUTE_API_PREFIX + "projects/p/"
+    tests := []struct {
+        name        string
+        mig         *compute.InstanceGroupManager
+        autoscaler  *compute.Autoscaler
+        want        migStatus
+    }{
+        {
+            "zonal without status",
+            &compute.InstanceGroupManager{Name: "web", Zone: prefix + "zones/us-central1-a", TargetSize: 3},
+            nil,
+            migStatus{
+                Name:           "web",
+                Location:       "us-central1-a",
+                TargetSize:     3,
+                CurrentActions: map[string]int64{},
+                Versions:       []migVersion{},
+            },
+        },
+        {
+            "regional canary with autoscaler",
+            &compute.InstanceGroupManager{
+                Name:           "api",
+                Region:         prefix + "regions/us-central1",
+                TargetSize:     4,
+                CurrentActions: &compute.InstanceGroupManagerActionsSummary{None: 3, Recreating: 1},
+                Status: &compute.InstanceGroupManagerStatus{
+                    IsStable:      false,
+                    VersionTarget: &compute.InstanceGroupManagerStatusVersionTarget{IsReached: false},
+                },
+                Versions: []*compute.InstanceGroupManagerVersion{
+                    {Name: "stable", InstanceTemplate: prefix + "global/instanceTemplates/api-v1"},
+                    {Name: "canary", InstanceTemplate: prefix + "global/instanceTemplates/api-v2", TargetSize: &compute.FixedOrPercent{Percent: 25, Calculated: 1}},
+                },
+            },
+            &compute.Autoscaler{
+                Name:              "api-as",
+                Status:            "ACTIVE",
+                RecommendedSize:   5,
+                AutoscalingPolicy: &compute.AutoscalingPolicy{MinNumReplicas: 2, MaxNumReplicas: 10, Mode: "ON"},
+                StatusDetails:     []*compute.AutoscalerStatusDetails{{Message: "scaling limited"}},
+            },
+            migStatus{
+                Name:       "api",
+                Location:   "us-central1",
+                Regional:   true,
+                TargetSize: 4,
+                CurrentActions: map[string]int64{
+                    "none": 3, "creating": 0, "creatingWithoutRetries": 0, "recreating": 1, "deleting": 0,
+                    "abandoning": 0, "restarting": 0, "refreshing": 0, "verifying": 0,
+                },
+                Versions: []migVersion{
+                    {Name: "stable", InstanceTemplate: "api-v1"},
+                    {Name: "canary", InstanceTemplate: "api-v2", TargetSize: 1},
+                },
+                Autoscaler: &migAutoscaler{
+                    Name:            "api-as",
+                    Status:          "ACTIVE",
+                    RecommendedSize: 5,
+                    MinReplicas:     2,
+                    MaxReplicas:     10,
+                    Mode:            "ON",
+                    StatusDetails:   []string{"scaling limited"},
+                },
+            },
+        },
+        {
+            "stable and version target reached",
+            &compute.InstanceGroupManager{
+                Name:   "batch",
+                Zone:   prefix + "zones/europe-west1-b",
+                Status: &compute.InstanceGroupManagerStatus{IsStable: true, VersionTarget: &compute.InstanceGroupManagerStatusVersionTarget{IsReached: true}},
+            },
+            nil,
+            migStatus{
+                Name:                 "batch",
+                Location:             "europe-west1-b",
+                CurrentActions:       map[string]int64{},
+                IsStable:             true,
+                VersionTargetReached: true,
+                Versions:             []migVersion{},
+            },
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := newMigStatus(tt.mig, tt.autoscaler); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("got %+v, want %+v", got, tt.want)
+            }
+        })
+    }
+}
@@ -0,0 +1,239 @@
+package metricsexporter
+/**
+ * Feed of the Compute Engine operations, including the system events of
+ * the instances: preemptions, live migrations, host errors and automatic
+ * restarts.
+ *
+ * @see https://cloud.google.com/compute/docs/instances/monitor-plan-host-maintenance-event
+ * @see https://cloud.google.com/compute/docs/instances/preemptible#detecting_if_an_instance_was_preempted
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "regexp"
+    "sort"
+    "strings"
+    "time"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* Event names of the operation types of the instance system events.
+ */
+var(
+    ComputeSystemEvents = map[string]string{
+        "compute.instances.preempted":                "preemption",
+        "compute.instances.migrateOnHostMaintenance": "live_migration",
+        "compute.instances.hostError":                "host_error",
+        "compute.instances.automaticRestart":         "automatic_restart",
+        "compute.instances.guestTerminate":           "guest_terminate",
+    }
+
+    // Operation types are inserted in the list filter: no quotes nor spaces.
+    operationTypePattern = regexp.MustCompile(`^[a-zA-Z.]+$`)
+)
+
+/* Compute operation.
+ */
+type computeOperation struct {
+    Name         string  `json:"name"`
+    Type         string  `json:"type"`
+    Event        string  `json:"event,omitempty"`
+    Status       string  `json:"status"`
+    Target       string  `json:"target"`
+    Location     string  `json:"location"`
+    MachineType  string  `json:"machineType,omitempty"`
+    User         string  `json:"user,omitempty"`
+    InsertTime   string  `json:"insertTime"`
+    EndTime      string  `json:"endTime,omitempty"`
+    Error        string  `json:"error,omitempty"`
+}
+
+/* Number of system events per location (zone, region or global) and
+ * machine type.
+ */
+type computeEventCount struct {
+    Event        string  `json:"event"`
+    Location     string  `json:"location"`
+    MachineType  string  `json:"machineType"`
+    Count        int     `json:"count"`
+}
+
+/* Operations feed.
+ */
+type computeOperationsFeed struct {
+    Operations  []computeOperation   `json:"operations"`
+    Events      []computeEventCount  `json:"events"`
+}
+
+/* getOperationsList lists the operations of the zone, else of the region,
+ * else of every scope (zone "-"). Set Arg1 to a duration (eg- "24h") to
+ * only include the operations inserted within that time window, and the
+ * operation type (eg- "compute.instances.preempted") to only include
+ * operations of that type. Both are sent in the list filter, the time window
+ * is also checked on the returned operations.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/globalOperations/aggregatedList
+ */
+func (n *Compute) getOperationsList(window, operationType string) (string, error) {
+    var since time.Time
+    if window != "" {
+        d, err := time.ParseDuration(window)
+        if err != nil {
+            return fmt.Sprintf("invalid time window: "), err
+        }
+        since = time.Now().Add(-d)
+    }
+    filter, err := operationsFilter(operationType, since)
+    if err != nil {
+        return fmt.Sprintf("invalid operation type: "), err
+    }
+
+    var ops []*compute.Operation
+    collect := func(list *compute.OperationList) error {
+        ops = append(ops, list.Items...)
+        return nil
+    }
+    scope := "-"
+    if n.Zone != "" && n.Zone != "-" {
+        scope = n.Zone
+        call := n.client.ZoneOperations.List(n.Project, n.Zone)
+        if filter != "" {
+            call = call.Filter(filter)
+        }
+        err = call.Pages(n.context, collect)
+    } else if n.Region != "" && n.Region != "-" {
+        scope = n.Region
+        call := n.client.RegionOperations.List(n.Project, n.Region)
+        if filter != "" {
+            call = call.Filter(filter)
+        }
+        err = call.Pages(n.context, collect)
+    } else {
+        call := n.client.GlobalOperations.AggregatedList(n.Project)
+        if filter != "" {
+            call = call.Filter(filter)
+        }
+        err = call.Pages(n.context, func(list *compute.OperationAggregatedList) error {
+            for _, scoped := range list.Items {
+                ops = append(ops, scoped.Operations...)
+            }
+            return nil
+        })
+    }
+    if err != nil {
+        return fmt.Sprintf("failed to list operations: "), err
+    }
+
+    // Machine types of the instances targeted by the events.
+    machineTypes := map[string]string{}
+    err = n.client.Instances.AggregatedList(n.Project).Pages(n.context, func(list *compute.InstanceAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, i := range scoped.Instances {
+                machineTypes[i.SelfLink] = lastPathElement(i.MachineType)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list instances: "), err
+    }
+
+    res := computeOperationsFeed{
+        Operations: []computeOperation{},
+        Events:     []computeEventCount{},
+    }
+    counts := map[computeEventCount]int{}
+    for _, op := range ops {
+        inserted, err := time.Parse(time.RFC3339, op.InsertTime)
+        if err == nil && !since.IsZero() && inserted.Before(since) {
+            continue
+        }
+        o := computeOperation{
+            Name:       op.Name,
+            Type:       op.OperationType,
+            Event:      ComputeSystemEvents[op.OperationType],
+            Status:     op.Status,
+            Target:     lastPathElement(op.TargetLink),
+            Location:   lastPathElement(op.Zone),
+            User:       op.User,
+            InsertTime: op.InsertTime,
+            EndTime:    op.EndTime,
+        }
+        if o.Location == "" {
+            o.Location = lastPathElement(op.Region)
+        }
+        if o.Location == "" {
+            o.Location = SCOPE_GLOBAL
+        }
+        if op.Error != nil && len(op.Error.Errors) > 0 {
+            o.Error = op.Error.Errors[0].Message
+        }
+        if o.Event != "" {
+            o.MachineType = machineTypes[op.TargetLink]
+            // The instance may have been deleted since.
+            machineType := o.MachineType
+            if machineType == "" {
+                machineType = "unknown"
+            }
+            counts[computeEventCount{Event: o.Event, Location: o.Location, MachineType: machineType}]++
+        }
+        res.Operations = append(res.Operations, o)
+    }
+    for k, v := range counts {
+        k.Count = v
+        res.Events = append(res.Events, k)
+    }
+    sort.Slice(res.Events, func(i, j int) bool {
+        a, b := res.Events[i], res.Events[j]
+        if a.Event != b.Event {
+            return a.Event < b.Event
+        }
+        if a.Location != b.Location {
+            return a.Location < b.Location
+        }
+        return a.MachineType < b.MachineType
+    })
+
+    if n.EnableEmitter {
+        events := newGaugeVec("gcp_compute_instance_events", "Number of instance system events in the time window.", "project", "location", "machine_type", "event")
+        for _, e := range res.Events {
+            events.WithLabelValues(n.Project, e.Location, e.MachineType, e.Event).Set(float64(e.Count))
+        }
+        // Each zone, region and operation type is a separate query.
+        target := "compute.operations.list." + scope
+        if operationType != "" {
+            target = target + "." + operationType
+        }
+        if err := emitCollectors(n.emitter, target, n.Project, events); err != nil {
+            return fmt.Sprintf("failed to emit operation metrics: "), err
+        }
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* operationsFilter returns the list filter of the operations of
+ * operationType inserted after since, both optional.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/globalOperations/aggregatedList#query-parameters
+ */
+func operationsFilter(operationType string, since time.Time) (string, error) {
+    var exprs []string
+    if operationType != "" {
+        if !operationTypePattern.MatchString(operationType) {
+            return "", fmt.Errorf("operation type %q is not a dotted name", operationType)
+        }
+        exprs = append(exprs, fmt.Sprintf(`operationType = "%s"`, operationType))
+    }
+    if !since.IsZero() {
+        exprs = append(exprs, fmt.Sprintf(`insertTime > "%s"`, since.UTC().Format(time.RFC3339)))
+    }
+    if len(exprs) < 2 {
+        return strings.Join(exprs, ""), nil
+    }
+    return "(" + strings.Join(exprs, ") (") + ")", nil
+}
@@ -0,0 +1,30 @@
+package metricsexporter
+
+import (
+    "testing"
+    "time"
+)
+
+func TestOperationsFilter(t *testing.T) {
+    since := time.Date(2020, 1, 1, 12, 0, 0, 0, time.FixedZone("", 3600))
+    tests := []struct {
+        operationType  string
+        since          time.Time
+        want           string
+        wantErr        bool
+    }{
+        {"", time.Time{}, "", false},
+        {"compute.instances.preempted", time.Time{}, `operationType = "compute.instances.preempted"`, false},
+        {"", since, `insertTime > "2020-01-01T11:00:00Z"`, false},
+        {"stop", since, `(operationType = "stop") (insertTime > "2020-01-01T11:00:00Z")`, false},
+        {`stop" OR name = "x`, time.Time{}, "", true},
+        {"compute instances", time.Time{}, "", true},
+        {"compute.instances.preempted)", time.Time{}, "", true},
+    }
+    for _, tt := range tests {
+        got, err := operationsFilter(tt.operationType, tt.since)
+        if (err != nil) != tt.wantErr || got != tt.want {
+            t.Errorf("operationsFilter(%q, %v) = %q, %v, want %q", tt.operationType, tt.since, got, err, tt.want)
+        }
+    }
+}
@@ -119,6 +119,34 @@ func (n *Compute) Do(qry Query) (string, error) {
         return n.getRegionsList()
     } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "instances.list" {
         return n.getInstancesList()
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "quotas.list" {
+        return n.getQuotasList()
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "regionquotas.list" {
+        return n.getRegionQuotasList()
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "waste" {
+        return n.getWaste(qry.Arg1, qry.Retention)
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "disks.list" {
+        return n.getDisksList()
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "snapshots.list" {
+        return n.getSnapshotsList()
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "images.list" {
+        return n.getImagesList(qry.Arg1)
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "machinetypes.list" {
+        return n.getMachineTypesList()
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "instancegroups.list" {
+        return n.getInstanceGroupsList()
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "migs.list" {
+        return n.getMigsList()
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "operations.list" {
+        return n.getOperationsList(qry.Arg1, qry.OperationType)
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "instances.audit" {
+        return n.getInstancesAudit()
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "reservations.utilization" {
+        return n.getReservationsUtilization()
+    } else if qry.Resource == "compute" && qry.Action == "get" && qry.Target == "commitments.utilization" {
+        return n.getCommitmentsUtilization()
+    } else if qry.Resource == "compute" && qry.Action == "cost" && qry.Target == "estimate" {
+        return n.getCostEstimate(qry.Arg1, qry.Label)
     }
     return "[Debug] It will call some Compute operations to return json response", nil
 }
@@ -0,0 +1,204 @@
+package metricsexporter
+/**
+ * Local price catalog used to estimate the cost of resources.
+ *
+ * On-demand list prices in USD of us-central1, with multipliers for the
+ * other regions. They are estimates, not billing data. Set the environment
+ * variable PRICE_CATALOG_FILE to a json file to override any of them.
+ *
+ * @see https://cloud.google.com/compute/all-pricing
+ * @see https://cloud.google.com/compute/docs/sustained-use-discounts
+ **/
+
+import (
+    "encoding/json"
+    "io/ioutil"
+    "os"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+const (
+    HOURS_PER_MONTH   = 730
+    PRICE_CATALOG_ENV = "PRICE_CATALOG_FILE"
+)
+
+/* Hourly prices of the vCPUs and memory of a machine family.
+ * SustainedUseDiscount is the discount of an instance running all month.
+ */
+type machineFamilyPrice struct {
+    VcpuHour              float64  `json:"vcpuHour"`
+    MemoryGbHour          float64  `json:"memoryGbHour"`
+    SustainedUseDiscount  float64  `json:"sustainedUseDiscount"`
+}
+
+/* Prices of the compute, storage and network resources.
+ */
+type priceTable struct {
+    MachineFamilies      map[string]machineFamilyPrice  `json:"machineFamilies"`
+    MachineTypeHour      map[string]float64             `json:"machineTypeHour"`
+    PreemptibleDiscount  float64                        `json:"preemptibleDiscount"`
+    RegionMultipliers    map[string]float64             `json:"regionMultipliers"`
+    DiskGbMonth          map[string]float64             `json:"diskGbMonth"`
+    RegionalDiskGbMonth  map[string]float64             `json:"regionalDiskGbMonth"`
+    SnapshotGbMonth      float64                        `json:"snapshotGbMonth"`
+    ImageGbMonth         float64                        `json:"imageGbMonth"`
+    StaticIpMonth        float64                        `json:"staticIpMonth"`
+    ExternalIpHour       float64                        `json:"externalIpHour"`
+    ForwardingRuleHour   float64                        `json:"forwardingRuleHour"`
+    VpnTunnelHour        float64                        `json:"vpnTunnelHour"`
+}
+
+/* newDefaultPriceTable returns the bundled prices.
+ */
+func newDefaultPriceTable() priceTable {
+    return priceTable{
+        MachineFamilies: map[string]machineFamilyPrice{
+            "n1":  {0.031611, 0.004237, 0.30},
+            "n2":  {0.031611, 0.004237, 0.20},
+            "n2d": {0.027502, 0.003686, 0.20},
+            "c2":  {0.03398, 0.00455, 0.20},
+            "c2d": {0.029563, 0.003959, 0.20},
+            "m1":  {0.0348, 0.0051, 0.30},
+            "e2":  {0.021811, 0.002923, 0},
+            "t2d": {0.027502, 0.003686, 0},
+        },
+        // Shared-core machine types.
+        MachineTypeHour: map[string]float64{
+            "f1-micro":  0.0076,
+            "g1-small":  0.0257,
+            "e2-micro":  0.00838,
+            "e2-small":  0.01675,
+            "e2-medium": 0.03351,
+        },
+        PreemptibleDiscount: 0.70,
+        RegionMultipliers: map[string]float64{
+            "us-central1":             1.0,
+            "us-east1":                1.0,
+            "us-west1":                1.0,
+            "us-east4":                1.126,
+            "us-west2":                1.2,
+            "northamerica-northeast1": 1.1,
+            "southamerica-east1":      1.59,
+            "europe-west1":            1.1,
+            "europe-west2":            1.2,
+            "europe-west3":            1.2,
+            "europe-west4":            1.1,
+            "asia-east1":              1.16,
+            "asia-northeast1":         1.28,
+            "asia-southeast1":         1.23,
+            "australia-southeast1":    1.41,
+        },
+        DiskGbMonth: map[string]float64{
+            "pd-standard": 0.04,
+            "pd-balanced": 0.10,
+            "pd-ssd":      0.17,
+            "pd-extreme":  0.125,
+        },
+        // Regional disks are replicated in two zones.
+        RegionalDiskGbMonth: map[string]float64{
+            "pd-standard": 0.08,
+            "pd-balanced": 0.20,
+            "pd-ssd":      0.34,
+            "pd-extreme":  0.25,
+        },
+        SnapshotGbMonth:    0.026,
+        ImageGbMonth:       0.085,
+        StaticIpMonth:      0.01 * HOURS_PER_MONTH,
+        ExternalIpHour:     0.004,
+        // Price of the first 5 forwarding rules, each additional rule costs less.
+        ForwardingRuleHour: 0.025,
+        VpnTunnelHour:      0.05,
+    }
+}
+
+/* loadPriceTable returns the bundled prices, overridden by the json file
+ * named in PRICE_CATALOG_FILE if set. The file only needs the prices it overrides.
+ */
+func loadPriceTable() (priceTable, error) {
+    path := os.Getenv(PRICE_CATALOG_ENV)
+    if path == "" {
+        return newDefaultPriceTable(), nil
+    }
+    bt, err := ioutil.ReadFile(path)
+    if err != nil {
+        return newDefaultPriceTable(), err
+    }
+    return parsePriceTable(bt)
+}
+
+/* parsePriceTable returns the bundled prices overridden by the json bt.
+ */
+func parsePriceTable(bt []byte) (priceTable, error) {
+    res := newDefaultPriceTable()
+    // Unmarshalling into the default maps only replaces the keys of the file,
+    // but replaces the whole price of a machine family: merge those per field.
+    var overrides struct {
+        MachineFamilies  map[string]json.RawMessage  `json:"machineFamilies"`
+    }
+    if err := json.Unmarshal(bt, &overrides); err != nil {
+        return res, err
+    }
+    if err := json.Unmarshal(bt, &res); err != nil {
+        return res, err
+    }
+    defaults := newDefaultPriceTable().MachineFamilies
+    for family, raw := range overrides.MachineFamilies {
+        price := defaults[family]
+        if err := json.Unmarshal(raw, &price); err != nil {
+            return res, err
+        }
+        res.MachineFamilies[family] = price
+    }
+    return res, nil
+}
+
+/* regionMultiplier is the price multiplier of region (or of the region of
+ * a zone). Unknown regions are priced as us-central1.
+ */
+func (p priceTable) regionMultiplier(location string) float64 {
+    if m, ok := p.RegionMultipliers[location]; ok {
+        return m
+    }
+    if m, ok := p.RegionMultipliers[zoneRegion(location)]; ok {
+        return m
+    }
+    return 1.0
+}
+
+/* instanceHourlyCost is the on-demand hourly cost of machineType of shape,
+ * and the sustained use discount of its family.
+ */
+func (p priceTable) instanceHourlyCost(machineType string, shape machineShape) (float64, float64) {
+    family := machineFamily(machineType)
+    price := p.MachineFamilies[family]
+    if hourly, ok := p.MachineTypeHour[machineType]; ok {
+        return hourly, price.SustainedUseDiscount
+    }
+    if _, ok := p.MachineFamilies[family]; !ok {
+        price = p.MachineFamilies["n1"]
+    }
+    hourly := float64(shape.Vcpus) * price.VcpuHour + float64(shape.MemoryMb) / 1024 * price.MemoryGbHour
+    return hourly, price.SustainedUseDiscount
+}
+
+/* diskMonthlyCost is the monthly cost of disk d, zonal or regional.
+ * Unknown disk types are priced as standard disks.
+ */
+func (p priceTable) diskMonthlyCost(d *compute.Disk) float64 {
+    rates := p.DiskGbMonth
+    if d.Zone == "" && d.Region != "" {
+        rates = p.RegionalDiskGbMonth
+    }
+    price, ok := rates[lastPathElement(d.Type)]
+    if !ok {
+        price = rates["pd-standard"]
+    }
+    return price * float64(d.SizeGb)
+}
+
+/* bytesToGb converts bytes to GB as billed (2^30 bytes).
+ */
+func bytesToGb(bytes int64) float64 {
+    return float64(bytes) / (1 << 30)
+}
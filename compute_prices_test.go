@@ -0,0 +1,97 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestMachineFamily(t *testing.T) {
+    tests := []struct {
+        machineType  string
+        want         string
+    }{
+        {"n1-standard-4", "n1"},
+        {"n2-highmem-8", "n2"},
+        {"n2d-custom-4-16384", "n2d"},
+        {"e2-medium", "e2"},
+        {"custom-2-4096", "n1"},
+        {"f1-micro", "f1"},
+        {"", ""},
+    }
+    for _, tt := range tests {
+        if got := machineFamily(tt.machineType); got != tt.want {
+            t.Errorf("machineFamily(%s) = %s, want %s", tt.machineType, got, tt.want)
+        }
+    }
+}
+
+func TestGkeNodePool(t *testing.T) {
+    tests := []struct {
+        kubeLabels  string
+        want        string
+    }{
+        {"cloud.google.com/gke-nodepool=default-pool", "default-pool"},
+        {"cloud.google.com/gke-os-distribution=cos,cloud.google.com/gke-nodepool=pool-1,env=prod", "pool-1"},
+        {"env=prod", "unknown"},
+        {"", "unknown"},
+    }
+    for _, tt := range tests {
+        if got := gkeNodePool(tt.kubeLabels); got != tt.want {
+            t.Errorf("gkeNodePool(%s) = %s, want %s", tt.kubeLabels, got, tt.want)
+        }
+    }
+}
+
+func TestParsePriceTable(t *testing.T) {
+    defaults := newDefaultPriceTable()
+
+    p, err := parsePriceTable([]byte(`{"machineFamilies": {"n1": {"vcpuHour": 0.05}, "a2": {"vcpuHour": 0.04}}, "diskGbMonth": {"pd-ssd": 0.2}, "staticIpMonth": 5}`))
+    if err != nil {
+        t.Fatalf("parsePriceTable() error = %v", err)
+    }
+    n1 := p.MachineFamilies["n1"]
+    if n1.VcpuHour != 0.05 || n1.MemoryGbHour != defaults.MachineFamilies["n1"].MemoryGbHour || n1.SustainedUseDiscount != defaults.MachineFamilies["n1"].SustainedUseDiscount {
+        t.Errorf("n1 = %+v, want only the vCPU price overridden", n1)
+    }
+    if p.MachineFamilies["a2"].VcpuHour != 0.04 {
+        t.Errorf("a2 = %+v, want the new family", p.MachineFamilies["a2"])
+    }
+    if p.MachineFamilies["n2"] != defaults.MachineFamilies["n2"] {
+        t.Errorf("n2 = %+v, want the default price", p.MachineFamilies["n2"])
+    }
+    if p.DiskGbMonth["pd-ssd"] != 0.2 || p.DiskGbMonth["pd-standard"] != defaults.DiskGbMonth["pd-standard"] {
+        t.Errorf("disk prices = %v", p.DiskGbMonth)
+    }
+    if p.StaticIpMonth != 5 || p.SnapshotGbMonth != defaults.SnapshotGbMonth {
+        t.Errorf("static ip = %v, snapshot = %v", p.StaticIpMonth, p.SnapshotGbMonth)
+    }
+
+    if _, err := parsePriceTable([]byte(`{"machineFamilies": {"n1": {"vcpuHour": "cheap"}}}`)); err == nil {
+        t.Errorf("parsePriceTable() accepted an invalid price")
+    }
+}
+
+func TestDiskMonthlyCost(t *testing.T) {
+    p := newDefaultPriceTable()
+    prefix := COMPUTE_API_PREFIX + "projects/p/"
+    tests := []struct {
+        name      string
+        disk      *compute.Disk
+        want      float64
+        location  string
+    }{
+        {"zonal", &compute.Disk{Zone: prefix + "zones/us-central1-a", Type: prefix + "zones/us-central1-a/diskTypes/pd-ssd", SizeGb: 100}, 17, "us-central1-a"},
+        {"regional", &compute.Disk{Region: prefix + "regions/us-central1", Type: prefix + "regions/us-central1/diskTypes/pd-ssd", SizeGb: 100}, 34, "us-central1"},
+        {"regional extreme", &compute.Disk{Region: prefix + "regions/us-central1", Type: prefix + "regions/us-central1/diskTypes/pd-extreme", SizeGb: 100}, 25, "us-central1"},
+        {"unknown type", &compute.Disk{Zone: prefix + "zones/us-central1-a", Type: "local", SizeGb: 10}, 0.4, "us-central1-a"},
+    }
+    for _, tt := range tests {
+        if got := p.diskMonthlyCost(tt.disk); got < tt.want - 1e-9 || got > tt.want + 1e-9 {
+            t.Errorf("%s: diskMonthlyCost() = %v, want %v", tt.name, got, tt.want)
+        }
+        if got := diskLocation(tt.disk); got != tt.location {
+            t.Errorf("%s: diskLocation() = %s, want %s", tt.name, got, tt.location)
+        }
+    }
+}
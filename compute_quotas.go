@@ -0,0 +1,97 @@
+package metricsexporter
+/**
+ * Project and regional Compute Engine quota utilization.
+ *
+ * @see https://cloud.google.com/compute/quotas
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* Usage of a single quota metric.
+ */
+type computeQuota struct {
+    Metric  string   `json:"metric"`
+    Scope   string   `json:"scope"`
+    Usage   float64  `json:"usage"`
+    Limit   float64  `json:"limit"`
+    Ratio   float64  `json:"ratio"`
+}
+
+/* @see https://cloud.google.com/compute/docs/reference/rest/v1/projects/get
+ */
+func (n *Compute) getQuotasList() (string, error) {
+    proj, err := n.client.Projects.Get(n.Project).Do()
+    if err != nil {
+        return fmt.Sprintf("failed to get project: "), err
+    }
+    return n.quotasResponse("compute.quotas.list", newComputeQuotas(SCOPE_GLOBAL, proj.Quotas))
+}
+
+/* @see https://cloud.google.com/compute/docs/reference/rest/v1/regions/get
+ */
+func (n *Compute) getRegionQuotasList() (string, error) {
+    region, err := n.client.Regions.Get(n.Project, n.Region).Do()
+    if err != nil {
+        return fmt.Sprintf("failed to get region: "), err
+    }
+    // Every region is pushed under its own target not to replace the others.
+    return n.quotasResponse("compute.regionquotas.list." + region.Name, newComputeQuotas(region.Name, region.Quotas))
+}
+
+/* quotasResponse emits quotas as metrics of target and returns them as json.
+ */
+func (n *Compute) quotasResponse(target string, quotas []computeQuota) (string, error) {
+    if err := n.emitQuotas(target, quotas); err != nil {
+        return fmt.Sprintf("failed to emit quota metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(quotas, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newComputeQuotas converts the quotas of scope (a region name, or
+ * "global" for project-wide quotas) and works out their utilization.
+ */
+func newComputeQuotas(scope string, quotas []*compute.Quota) []computeQuota {
+    var res []computeQuota
+    for _, q := range quotas {
+        cq := computeQuota{
+            Metric: q.Metric,
+            Scope:  scope,
+            Usage:  q.Usage,
+            Limit:  q.Limit,
+        }
+        if q.Limit > 0 {
+            cq.Ratio = q.Usage / q.Limit
+        }
+        res = append(res, cq)
+    }
+    return res
+}
+
+/* emitQuotas sends the usage and limit of every quota as metrics.
+ */
+func (n *Compute) emitQuotas(target string, quotas []computeQuota) error {
+    if !n.EnableEmitter {
+        return nil
+    }
+
+    labels := []string{"project", "region", "metric"}
+    usage := newGaugeVec("gcp_quota_usage", "Current usage of a Compute Engine quota.", labels...)
+    limit := newGaugeVec("gcp_quota_limit", "Limit of a Compute Engine quota.", labels...)
+
+    for _, q := range quotas {
+        usage.WithLabelValues(n.Project, q.Scope, q.Metric).Set(q.Usage)
+        limit.WithLabelValues(n.Project, q.Scope, q.Metric).Set(q.Limit)
+    }
+
+    return emitCollectors(n.emitter, target, n.Project, usage, limit)
+}
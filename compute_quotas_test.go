@@ -0,0 +1,40 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestNewComputeQuotas(t *testing.T) {
+    quotas := []*compute.Quota{
+        {Metric: "CPUS", Usage: 12, Limit: 24},
+        {Metric: "SSD_TOTAL_GB", Usage: 0, Limit: 2048},
+        {Metric: "UNLIMITED", Usage: 5, Limit: 0},
+    }
+    tests := []struct {
+        metric  string
+        usage   float64
+        limit   float64
+        ratio   float64
+    }{
+        {"CPUS", 12, 24, 0.5},
+        {"SSD_TOTAL_GB", 0, 2048, 0},
+        {"UNLIMITED", 5, 0, 0},
+    }
+
+    res := newComputeQuotas(SCOPE_GLOBAL, quotas)
+    if len(res) != len(tests) {
+        t.Fatalf("got %d quotas, want %d", len(res), len(tests))
+    }
+    for i, tt := range tests {
+        q := res[i]
+        if q.Metric != tt.metric || q.Scope != SCOPE_GLOBAL || q.Usage != tt.usage || q.Limit != tt.limit || q.Ratio != tt.ratio {
+            t.Errorf("got %+v, want %s %s %v/%v ratio %v", q, tt.metric, SCOPE_GLOBAL, tt.usage, tt.limit, tt.ratio)
+        }
+    }
+
+    if res := newComputeQuotas("us-central1", nil); len(res) != 0 {
+        t.Errorf("got %d quotas without input, want 0", len(res))
+    }
+}
@@ -0,0 +1,436 @@
+package metricsexporter
+/**
+ * Utilization of the zonal reservations and of the regional committed use
+ * discounts, compared to the running instances.
+ *
+ * @see https://cloud.google.com/compute/docs/instances/reservations-overview
+ * @see https://cloud.google.com/compute/docs/instances/signing-up-committed-use-discounts
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "io/ioutil"
+    "net/http"
+    "net/url"
+    "strconv"
+    "strings"
+
+    compute     "google.golang.org/api/compute/v1"
+    option      "google.golang.org/api/option"
+    htransport  "google.golang.org/api/transport/http"
+)
+
+/* Machine families covered by every commitment type. Commitments created
+ * before the types were introduced have no type and cover N1.
+ * @see https://cloud.google.com/compute/docs/instances/signing-up-committed-use-discounts#commitment_types
+ */
+var(
+    CommitmentTypeFamilies = map[string][]string{
+        "":                      {"n1"},
+        "GENERAL_PURPOSE":       {"n1"},
+        "GENERAL_PURPOSE_N2":    {"n2"},
+        "GENERAL_PURPOSE_N2D":   {"n2d"},
+        "GENERAL_PURPOSE_E2":    {"e2"},
+        "GENERAL_PURPOSE_T2D":   {"t2d"},
+        "COMPUTE_OPTIMIZED":     {"c2"},
+        "COMPUTE_OPTIMIZED_C2D": {"c2d"},
+        "MEMORY_OPTIMIZED":      {"m1", "m2"},
+        "ACCELERATOR_OPTIMIZED": {"a2"},
+    }
+)
+
+/* vCPUs and memory of a machine type.
+ */
+type machineShape struct {
+    Vcpus     int64
+    MemoryMb  int64
+}
+
+/* vCPUs and memory per machine family, and number of instances per
+ * machine type, of the running instances of a zone.
+ */
+type zoneUsage struct {
+    Families      map[string]machineShape
+    MachineTypes  map[string]int64
+}
+
+/* Utilization of a reservation.
+ */
+type reservationUtilization struct {
+    Name               string   `json:"name"`
+    Zone               string   `json:"zone"`
+    MachineType        string   `json:"machineType"`
+    Count              int64    `json:"count"`
+    InUseCount         int64    `json:"inUseCount"`
+    SpecificRequired   bool     `json:"specificReservationRequired"`
+    MatchingInstances  int64    `json:"matchingInstances"`
+    NotConsuming       int64    `json:"matchingNotConsuming"`
+    ReservedVcpus      int64    `json:"reservedVcpus"`
+    ReservedMemoryMb   int64    `json:"reservedMemoryMb"`
+    UnusedVcpus        int64    `json:"unusedVcpus"`
+    UnusedMemoryMb     int64    `json:"unusedMemoryMb"`
+    Utilization        float64  `json:"utilization"`
+    Commitment         string   `json:"commitment,omitempty"`
+}
+
+/* Utilization of a resource (vCPU or memory) of a regional commitment.
+ */
+type commitmentUtilization struct {
+    Name         string   `json:"name"`
+    Region       string   `json:"region"`
+    Plan         string   `json:"plan"`
+    Type         string   `json:"type"`
+    Families     []string `json:"machineFamilies"`
+    EndTime      string   `json:"endTimestamp"`
+    Resource     string   `json:"resource"`
+    Committed    int64    `json:"committed"`
+    Used         int64    `json:"used"`
+    Unused       int64    `json:"unused"`
+    Utilization  float64  `json:"utilization"`
+}
+
+/* getReservationsUtilization compares every reservation to the running
+ * instances of its zone with the same machine type. Running instances of
+ * that machine type beyond the ones consuming reservations are reported as
+ * matching but not consuming, up to the unused count of the reservation:
+ * they do not target it (specificReservationRequired) or opt out of it.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/reservations/aggregatedList
+ */
+func (n *Compute) getReservationsUtilization() (string, error) {
+    shapes, err := n.listMachineShapes(n.Project)
+    if err != nil {
+        return fmt.Sprintf("failed to list machine types: "), err
+    }
+    usage, err := n.listZoneUsage(shapes)
+    if err != nil {
+        return fmt.Sprintf("failed to list instances: "), err
+    }
+
+    res := []reservationUtilization{}
+    err = n.client.Reservations.AggregatedList(n.Project).Pages(n.context, func(list *compute.ReservationAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, r := range scoped.Reservations {
+                if r.SpecificReservation == nil || r.SpecificReservation.InstanceProperties == nil {
+                    continue
+                }
+                zone := lastPathElement(r.Zone)
+                machineType := lastPathElement(r.SpecificReservation.InstanceProperties.MachineType)
+                shape := machineTypeShape(shapes, zone, machineType)
+                ru := reservationUtilization{
+                    Name:             r.Name,
+                    Zone:             zone,
+                    MachineType:      machineType,
+                    Count:            r.SpecificReservation.Count,
+                    InUseCount:       r.SpecificReservation.InUseCount,
+                    SpecificRequired: r.SpecificReservationRequired,
+                    ReservedVcpus:    r.SpecificReservation.Count * shape.Vcpus,
+                    ReservedMemoryMb: r.SpecificReservation.Count * shape.MemoryMb,
+                    Commitment:       lastPathElement(r.Commitment),
+                }
+                if u, ok := usage[zone]; ok {
+                    ru.MatchingInstances = u.MachineTypes[machineType]
+                }
+                unused := ru.Count - ru.InUseCount
+                ru.UnusedVcpus = unused * shape.Vcpus
+                ru.UnusedMemoryMb = unused * shape.MemoryMb
+                if ru.Count > 0 {
+                    ru.Utilization = float64(ru.InUseCount) / float64(ru.Count)
+                }
+                res = append(res, ru)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list reservations: "), err
+    }
+    reconcileReservations(res)
+
+    if n.EnableEmitter {
+        labels := []string{"project", "zone", "reservation", "machine_type"}
+        ratio := newGaugeVec("gcp_reservation_utilization_ratio", "Ratio of the reserved instances in use.", labels...)
+        vcpus := newGaugeVec("gcp_reservation_unused_vcpus", "Number of reserved vCPUs not in use.", labels...)
+        memory := newGaugeVec("gcp_reservation_unused_memory_mb", "Reserved memory not in use in MB.", labels...)
+        notConsuming := newGaugeVec("gcp_reservation_matching_not_consuming_instances", "Number of running instances matching the reservation without consuming it.", labels...)
+        for _, r := range res {
+            ratio.WithLabelValues(n.Project, r.Zone, r.Name, r.MachineType).Set(r.Utilization)
+            vcpus.WithLabelValues(n.Project, r.Zone, r.Name, r.MachineType).Set(float64(r.UnusedVcpus))
+            memory.WithLabelValues(n.Project, r.Zone, r.Name, r.MachineType).Set(float64(r.UnusedMemoryMb))
+            notConsuming.WithLabelValues(n.Project, r.Zone, r.Name, r.MachineType).Set(float64(r.NotConsuming))
+        }
+        if err := emitCollectors(n.emitter, "compute.reservations.utilization", n.Project, ratio, vcpus, memory, notConsuming); err != nil {
+            return fmt.Sprintf("failed to emit reservation metrics: "), err
+        }
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* getCommitmentsUtilization compares the vCPUs and memory of every active
+ * regional commitment to the running instances of the zones of its region
+ * in the machine families of its type. Preemptible instances are not
+ * covered by commitments. Commitments of the same region and families
+ * share the usage, in the order they are listed.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/regionCommitments/aggregatedList
+ */
+func (n *Compute) getCommitmentsUtilization() (string, error) {
+    shapes, err := n.listMachineShapes(n.Project)
+    if err != nil {
+        return fmt.Sprintf("failed to list machine types: "), err
+    }
+    usage, err := n.listZoneUsage(shapes)
+    if err != nil {
+        return fmt.Sprintf("failed to list instances: "), err
+    }
+
+    commitments, types, err := n.listCommitments()
+    if err != nil {
+        return fmt.Sprintf("failed to list commitments: "), err
+    }
+
+    // Usage not yet covered by a commitment, per region, family and resource.
+    available := map[string]int64{}
+    for zone, u := range usage {
+        region := zoneRegion(zone)
+        for family, shape := range u.Families {
+            available[region + "/" + family + "/VCPU"] += shape.Vcpus
+            available[region + "/" + family + "/MEMORY"] += shape.MemoryMb
+        }
+    }
+
+    res := []commitmentUtilization{}
+    for _, c := range commitments {
+        if c.Status != "ACTIVE" {
+            continue
+        }
+        region := lastPathElement(c.Region)
+        commitmentType := types[c.SelfLink]
+        families := CommitmentTypeFamilies[commitmentType]
+        for _, r := range c.Resources {
+            if r.Type != "VCPU" && r.Type != "MEMORY" {
+                continue
+            }
+            used := int64(0)
+            for _, family := range families {
+                key := region + "/" + family + "/" + r.Type
+                take := available[key]
+                if take > r.Amount - used {
+                    take = r.Amount - used
+                }
+                available[key] -= take
+                used += take
+            }
+            cu := commitmentUtilization{
+                Name:      c.Name,
+                Region:    region,
+                Plan:      c.Plan,
+                Type:      commitmentType,
+                Families:  families,
+                EndTime:   c.EndTimestamp,
+                Resource:  r.Type,
+                Committed: r.Amount,
+                Used:      used,
+                Unused:    r.Amount - used,
+            }
+            if r.Amount > 0 {
+                cu.Utilization = float64(used) / float64(r.Amount)
+            }
+            res = append(res, cu)
+        }
+    }
+
+    if n.EnableEmitter {
+        labels := []string{"project", "region", "commitment", "resource"}
+        ratio := newGaugeVec("gcp_commitment_utilization_ratio", "Ratio of the committed resource used by running instances.", labels...)
+        unused := newGaugeVec("gcp_commitment_unused", "Committed resource not used (vCPUs, or memory in MB).", labels...)
+        for _, c := range res {
+            ratio.WithLabelValues(n.Project, c.Region, c.Name, c.Resource).Set(c.Utilization)
+            unused.WithLabelValues(n.Project, c.Region, c.Name, c.Resource).Set(float64(c.Unused))
+        }
+        if err := emitCollectors(n.emitter, "compute.commitments.utilization", n.Project, ratio, unused); err != nil {
+            return fmt.Sprintf("failed to emit commitment metrics: "), err
+        }
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* listMachineShapes returns the shape of every machine type of project by "zone/name".
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/machineTypes/aggregatedList
+ */
+func (n *Compute) listMachineShapes(project string) (map[string]machineShape, error) {
+    res := map[string]machineShape{}
+    err := n.client.MachineTypes.AggregatedList(project).Pages(n.context, func(list *compute.MachineTypeAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, m := range scoped.MachineTypes {
+                res[lastPathElement(m.Zone) + "/" + m.Name] = machineShape{m.GuestCpus, m.MemoryMb}
+            }
+        }
+        return nil
+    })
+    return res, err
+}
+
+/* listCommitments returns the commitments of the project, and the type of
+ * every commitment by self link. The compute client of this API version
+ * has no commitment type, so the aggregated list is read as json.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/regionCommitments/aggregatedList
+ */
+func (n *Compute) listCommitments() ([]*compute.Commitment, map[string]string, error) {
+    client, _, err := htransport.NewClient(n.context, option.WithScopes(compute.ComputeReadonlyScope))
+    if err != nil {
+        return nil, nil, err
+    }
+
+    var res []*compute.Commitment
+    types := map[string]string{}
+    pageToken := ""
+    for {
+        link := n.client.BasePath + url.PathEscape(n.Project) + "/aggregated/commitments"
+        if pageToken != "" {
+            link = link + "?pageToken=" + url.QueryEscape(pageToken)
+        }
+        req, err := http.NewRequest("GET", link, nil)
+        if err != nil {
+            return nil, nil, err
+        }
+        resp, err := client.Do(req.WithContext(n.context))
+        if err != nil {
+            return nil, nil, err
+        }
+        body, err := ioutil.ReadAll(resp.Body)
+        resp.Body.Close()
+        if err != nil {
+            return nil, nil, err
+        }
+        if resp.StatusCode != 200 {
+            return nil, nil, fmt.Errorf("GET %s: %s: %s", link, resp.Status, body)
+        }
+        var list struct {
+            Items  map[string]struct {
+                Commitments  []json.RawMessage  `json:"commitments"`
+            }  `json:"items"`
+            NextPageToken  string  `json:"nextPageToken"`
+        }
+        if err := json.Unmarshal(body, &list); err != nil {
+            return nil, nil, err
+        }
+        for _, scoped := range list.Items {
+            for _, raw := range scoped.Commitments {
+                c := &compute.Commitment{}
+                var t struct {
+                    Type  string  `json:"type"`
+                }
+                if err := json.Unmarshal(raw, c); err != nil {
+                    return nil, nil, err
+                }
+                if err := json.Unmarshal(raw, &t); err != nil {
+                    return nil, nil, err
+                }
+                res = append(res, c)
+                types[c.SelfLink] = t.Type
+            }
+        }
+        if list.NextPageToken == "" {
+            return res, types, nil
+        }
+        pageToken = list.NextPageToken
+    }
+}
+
+/* reconcileReservations sets the running instances of every reservation
+ * that match it but do not consume any reservation. The instances of a
+ * zone and machine type in excess of the ones consuming reservations are
+ * shared by the reservations with unused capacity, in order.
+ */
+func reconcileReservations(res []reservationUtilization) {
+    excess := map[string]int64{}
+    for _, r := range res {
+        excess[r.Zone + "/" + r.MachineType] = r.MatchingInstances
+    }
+    for _, r := range res {
+        excess[r.Zone + "/" + r.MachineType] -= r.InUseCount
+    }
+    for i := range res {
+        key := res[i].Zone + "/" + res[i].MachineType
+        n := res[i].Count - res[i].InUseCount
+        if n > excess[key] {
+            n = excess[key]
+        }
+        if n < 0 {
+            n = 0
+        }
+        res[i].NotConsuming = n
+        excess[key] -= n
+    }
+}
+
+/* listZoneUsage sums the vCPUs and memory of the running instances per
+ * zone and machine family. Preemptible instances consume no reservation
+ * and are not covered by commitments, so they are skipped.
+ */
+func (n *Compute) listZoneUsage(shapes map[string]machineShape) (map[string]*zoneUsage, error) {
+    res := map[string]*zoneUsage{}
+    err := n.client.Instances.AggregatedList(n.Project).Pages(n.context, func(list *compute.InstanceAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, i := range scoped.Instances {
+                if i.Status != "RUNNING" || (i.Scheduling != nil && i.Scheduling.Preemptible) {
+                    continue
+                }
+                zone := lastPathElement(i.Zone)
+                machineType := lastPathElement(i.MachineType)
+                if res[zone] == nil {
+                    res[zone] = &zoneUsage{Families: map[string]machineShape{}, MachineTypes: map[string]int64{}}
+                }
+                shape := machineTypeShape(shapes, zone, machineType)
+                family := res[zone].Families[machineFamily(machineType)]
+                family.Vcpus += shape.Vcpus
+                family.MemoryMb += shape.MemoryMb
+                res[zone].Families[machineFamily(machineType)] = family
+                res[zone].MachineTypes[machineType]++
+            }
+        }
+        return nil
+    })
+    return res, err
+}
+
+/* machineTypeShape returns the shape of a predefined machine type, or
+ * parses the name of a custom one such as "n2-custom-4-16384".
+ */
+func machineTypeShape(shapes map[string]machineShape, zone, machineType string) machineShape {
+    if s, ok := shapes[zone + "/" + machineType]; ok {
+        return s
+    }
+    parts := strings.Split(machineType, "-")
+    for i, p := range parts {
+        if p == "custom" && i + 2 < len(parts) {
+            cpus, err1 := strconv.ParseInt(parts[i + 1], 10, 64)
+            memory, err2 := strconv.ParseInt(parts[i + 2], 10, 64)
+            if err1 == nil && err2 == nil {
+                return machineShape{cpus, memory}
+            }
+        }
+    }
+    return machineShape{}
+}
+
+/* machineFamily returns the family of machineType, eg- "n2" for
+ * "n2-standard-4". Legacy custom machine types are n1.
+ */
+func machineFamily(machineType string) string {
+    family := strings.Split(machineType, "-")[0]
+    if family == "custom" {
+        return "n1"
+    }
+    return family
+}
@@ -0,0 +1,66 @@
+package metricsexporter
+
+import (
+    "testing"
+)
+
+func TestMachineTypeShape(t *testing.T) {
+    shapes := map[string]machineShape{
+        "us-central1-a/n1-standard-4": {4, 15360},
+    }
+    tests := []struct {
+        zone         string
+        machineType  string
+        want         machineShape
+    }{
+        {"us-central1-a", "n1-standard-4", machineShape{4, 15360}},
+        {"us-central1-b", "n1-standard-4", machineShape{}},
+        {"us-central1-a", "custom-2-4096", machineShape{2, 4096}},
+        {"us-central1-a", "n2-custom-4-16384", machineShape{4, 16384}},
+        {"us-central1-a", "n2d-custom-8-32768-ext", machineShape{8, 32768}},
+        {"us-central1-a", "n2-custom-x-16384", machineShape{}},
+        {"us-central1-a", "custom-2", machineShape{}},
+    }
+    for _, tt := range tests {
+        if got := machineTypeShape(shapes, tt.zone, tt.machineType); got != tt.want {
+            t.Errorf("machineTypeShape(%s, %s) = %+v, want %+v", tt.zone, tt.machineType, got, tt.want)
+        }
+    }
+}
+
+func TestZoneRegion(t *testing.T) {
+    tests := []struct {
+        zone  string
+        want  string
+    }{
+        {"us-central1-a", "us-central1"},
+        {"europe-west4-c", "europe-west4"},
+        {"us-central1", "us"},
+        {"global", "global"},
+        {"", ""},
+    }
+    for _, tt := range tests {
+        if got := zoneRegion(tt.zone); got != tt.want {
+            t.Errorf("zoneRegion(%s) = %s, want %s", tt.zone, got, tt.want)
+        }
+    }
+}
+
+func TestReconcileReservations(t *testing.T) {
+    res := []reservationUtilization{
+        // 5 running n2-standard-4 in the zone, 3 consume reservations.
+        {Name: "a", Zone: "z", MachineType: "n2-standard-4", Count: 2, InUseCount: 2, MatchingInstances: 5},
+        {Name: "b", Zone: "z", MachineType: "n2-standard-4", Count: 3, InUseCount: 1, MatchingInstances: 5},
+        {Name: "c", Zone: "z", MachineType: "n2-standard-4", Count: 1, InUseCount: 0, MatchingInstances: 5},
+        // Every matching instance consumes the reservation.
+        {Name: "d", Zone: "z", MachineType: "e2-medium", Count: 4, InUseCount: 2, MatchingInstances: 2},
+        {Name: "e", Zone: "z", MachineType: "c2-standard-8", Count: 1, InUseCount: 0, MatchingInstances: 0},
+    }
+    reconcileReservations(res)
+    want := map[string]int64{"a": 0, "b": 2, "c": 0, "d": 0, "e": 0}
+    for _, r := range res {
+        if r.NotConsuming != want[r.Name] {
+            t.Errorf("reservation %s: %d matching not consuming, want %d", r.Name, r.NotConsuming, want[r.Name])
+        }
+    }
+}
@@ -0,0 +1,205 @@
+package metricsexporter
+/**
+ * Persistent disks, snapshots, images and machine types.
+ *
+ * @see https://cloud.google.com/compute/docs/disks
+ * @see https://cloud.google.com/compute/docs/images
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* getDisksList lists the disks of the zone, or of every zone if the zone
+ * is "-", and emits the provisioned GB per disk type and zone.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/disks/aggregatedList
+ */
+func (n *Compute) getDisksList() (string, error) {
+    var disks []*compute.Disk
+    var err error
+    if n.Zone == "" || n.Zone == "-" {
+        err = n.client.Disks.AggregatedList(n.Project).Pages(n.context, func(list *compute.DiskAggregatedList) error {
+            for _, scoped := range list.Items {
+                disks = append(disks, scoped.Disks...)
+            }
+            return nil
+        })
+    } else {
+        err = n.client.Disks.List(n.Project, n.Zone).Pages(n.context, func(list *compute.DiskList) error {
+            disks = append(disks, list.Items...)
+            return nil
+        })
+    }
+    if err != nil {
+        return fmt.Sprintf("failed to list disks: "), err
+    }
+
+    if n.EnableEmitter {
+        // The location of a regional disk is its region.
+        labels := []string{"project", "location", "type"}
+        size := newGaugeVec("gcp_disk_provisioned_gb", "Provisioned size of the disks in GB.", labels...)
+        count := newGaugeVec("gcp_disks", "Number of disks.", labels...)
+        for k, t := range summarizeDisks(disks) {
+            size.WithLabelValues(n.Project, k.Location, k.Type).Set(float64(t.SizeGb))
+            count.WithLabelValues(n.Project, k.Location, k.Type).Set(float64(t.Count))
+        }
+        zone := n.Zone
+        if zone == "" {
+            zone = "-"
+        }
+        if err := emitCollectors(n.emitter, "compute.disks.list." + zone, n.Project, size, count); err != nil {
+            return fmt.Sprintf("failed to emit disk metrics: "), err
+        }
+    }
+
+    var items []json.Marshaler
+    for _, d := range disks {
+        items = append(items, d)
+    }
+    return joinIndentJSON(items)
+}
+
+/* Location and type of disks.
+ */
+type diskGroup struct {
+    Location  string
+    Type      string
+}
+
+/* Number and provisioned size of disks.
+ */
+type diskTotal struct {
+    Count   int64
+    SizeGb  int64
+}
+
+/* summarizeDisks sums the number and provisioned size of the disks per
+ * location (zone, or region of regional disks) and disk type.
+ */
+func summarizeDisks(disks []*compute.Disk) map[diskGroup]diskTotal {
+    res := map[diskGroup]diskTotal{}
+    for _, d := range disks {
+        k := diskGroup{diskLocation(d), lastPathElement(d.Type)}
+        t := res[k]
+        t.Count++
+        t.SizeGb += d.SizeGb
+        res[k] = t
+    }
+    return res
+}
+
+/* diskLocation returns the zone of a zonal disk, or the region of a regional one.
+ */
+func diskLocation(d *compute.Disk) string {
+    if d.Zone == "" {
+        return lastPathElement(d.Region)
+    }
+    return lastPathElement(d.Zone)
+}
+
+/* getSnapshotsList lists the snapshots and emits their storage bytes.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/snapshots/list
+ */
+func (n *Compute) getSnapshotsList() (string, error) {
+    var snapshots []*compute.Snapshot
+    err := n.client.Snapshots.List(n.Project).Pages(n.context, func(list *compute.SnapshotList) error {
+        snapshots = append(snapshots, list.Items...)
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list snapshots: "), err
+    }
+
+    if n.EnableEmitter {
+        storage := newGaugeVec("gcp_snapshot_storage_bytes", "Storage used by the snapshot in bytes.", "project", "snapshot", "source_disk")
+        for _, s := range snapshots {
+            storage.WithLabelValues(n.Project, s.Name, lastPathElement(s.SourceDisk)).Set(float64(s.StorageBytes))
+        }
+        if err := emitCollectors(n.emitter, "compute.snapshots.list", n.Project, storage); err != nil {
+            return fmt.Sprintf("failed to emit snapshot metrics: "), err
+        }
+    }
+
+    var items []json.Marshaler
+    for _, s := range snapshots {
+        items = append(items, s)
+    }
+    return joinIndentJSON(items)
+}
+
+/* getImagesList lists the images of the project, or of the image project
+ * in Arg1 (such as debian-cloud). The deprecation status is in the
+ * "deprecated" field of every image.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/images/list
+ */
+func (n *Compute) getImagesList(imageProject string) (string, error) {
+    if imageProject == "" {
+        imageProject = n.Project
+    }
+    var images []*compute.Image
+    err := n.client.Images.List(imageProject).Pages(n.context, func(list *compute.ImageList) error {
+        images = append(images, list.Items...)
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list images: "), err
+    }
+
+    if n.EnableEmitter {
+        deprecated := newGaugeVec("gcp_images", "Number of images by deprecation state.", "project", "image_project", "state")
+        for _, i := range images {
+            deprecated.WithLabelValues(n.Project, imageProject, imageState(i)).Inc()
+        }
+        if err := emitCollectors(n.emitter, "compute.images.list." + imageProject, n.Project, deprecated); err != nil {
+            return fmt.Sprintf("failed to emit image metrics: "), err
+        }
+    }
+
+    var items []json.Marshaler
+    for _, i := range images {
+        items = append(items, i)
+    }
+    return joinIndentJSON(items)
+}
+
+/* getMachineTypesList lists the machine types available in the zone, or
+ * in every zone if the zone is "-".
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/machineTypes/aggregatedList
+ */
+func (n *Compute) getMachineTypesList() (string, error) {
+    var items []json.Marshaler
+    var err error
+    if n.Zone == "" || n.Zone == "-" {
+        err = n.client.MachineTypes.AggregatedList(n.Project).Pages(n.context, func(list *compute.MachineTypeAggregatedList) error {
+            for _, scoped := range list.Items {
+                for _, m := range scoped.MachineTypes {
+                    items = append(items, m)
+                }
+            }
+            return nil
+        })
+    } else {
+        err = n.client.MachineTypes.List(n.Project, n.Zone).Pages(n.context, func(list *compute.MachineTypeList) error {
+            for _, m := range list.Items {
+                items = append(items, m)
+            }
+            return nil
+        })
+    }
+    if err != nil {
+        return fmt.Sprintf("failed to list machine types: "), err
+    }
+    return joinIndentJSON(items)
+}
+
+/* imageState returns the deprecation state of image i, ACTIVE if not deprecated.
+ */
+func imageState(i *compute.Image) string {
+    if i.Deprecated != nil && i.Deprecated.State != "" {
+        return i.Deprecated.State
+    }
+    return "ACTIVE"
+}
@@ -0,0 +1,53 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestSummarizeDisks(t *testing.T) {
+    prefix := COMPUTE_API_PREFIX + "projects/p/"
+    zonal := func(zone, diskType string, size int64) *compute.Disk {
+        return &compute.Disk{Zone: prefix + "zones/" + zone, Type: prefix + "zones/" + zone + "/diskTypes/" + diskType, SizeGb: size}
+    }
+    disks := []*compute.Disk{
+        zonal("us-central1-a", "pd-ssd", 100),
+        zonal("us-central1-a", "pd-ssd", 50),
+        zonal("us-central1-a", "pd-standard", 10),
+        zonal("us-central1-b", "pd-ssd", 20),
+        {Region: prefix + "regions/us-central1", Type: prefix + "regions/us-central1/diskTypes/pd-ssd", SizeGb: 200},
+    }
+    want := map[diskGroup]diskTotal{
+        {"us-central1-a", "pd-ssd"}:      {2, 150},
+        {"us-central1-a", "pd-standard"}: {1, 10},
+        {"us-central1-b", "pd-ssd"}:      {1, 20},
+        {"us-central1", "pd-ssd"}:        {1, 200},
+    }
+    got := summarizeDisks(disks)
+    if len(got) != len(want) {
+        t.Errorf("summarizeDisks() = %v, want %v", got, want)
+    }
+    for k, v := range want {
+        if got[k] != v {
+            t.Errorf("summarizeDisks()[%v] = %+v, want %+v", k, got[k], v)
+        }
+    }
+}
+
+func TestImageState(t *testing.T) {
+    tests := []struct {
+        image  *compute.Image
+        want   string
+    }{
+        {&compute.Image{}, "ACTIVE"},
+        {&compute.Image{Deprecated: &compute.DeprecationStatus{}}, "ACTIVE"},
+        {&compute.Image{Deprecated: &compute.DeprecationStatus{State: "DEPRECATED"}}, "DEPRECATED"},
+        {&compute.Image{Deprecated: &compute.DeprecationStatus{State: "OBSOLETE"}}, "OBSOLETE"},
+    }
+    for _, tt := range tests {
+        if got := imageState(tt.image); got != tt.want {
+            t.Errorf("imageState(%+v) = %s, want %s", tt.image.Deprecated, got, tt.want)
+        }
+    }
+}
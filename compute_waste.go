@@ -0,0 +1,313 @@
+package metricsexporter
+/**
+ * Unused (forgotten) Compute Engine and network resources we still pay for.
+ *
+ * @see https://cloud.google.com/compute/docs/viewing-and-applying-idle-resources-recommendations
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    "github.com/prometheus/client_golang/prometheus"
+    compute  "google.golang.org/api/compute/v1"
+)
+
+const (
+    WASTE_STOPPED_DAYS   = 30
+    WASTE_RETENTION_DAYS = 90
+)
+
+/* Unused resource and its estimated monthly cost.
+ */
+type wasteFinding struct {
+    Kind         string   `json:"kind"`
+    Name         string   `json:"name"`
+    Location     string   `json:"location"`
+    Detail       string   `json:"detail"`
+    MonthlyCost  float64  `json:"estimatedMonthlyCost"`
+}
+
+/* Unused resources of the project.
+ */
+type wasteReport struct {
+    StoppedDays    int                 `json:"stoppedDays,omitempty"`
+    RetentionDays  int                 `json:"retentionDays,omitempty"`
+    Findings       []wasteFinding      `json:"findings"`
+    MonthlyCost    map[string]float64  `json:"estimatedMonthlyCostByKind"`
+    TotalCost      float64             `json:"estimatedMonthlyCost"`
+}
+
+/* newWasteReport returns an empty report.
+ */
+func newWasteReport() wasteReport {
+    return wasteReport{
+        Findings:    []wasteFinding{},
+        MonthlyCost: map[string]float64{},
+    }
+}
+
+/* add records an unused resource and its cost.
+ */
+func (r *wasteReport) add(kind, name, location, detail string, cost float64) {
+    r.Findings = append(r.Findings, wasteFinding{kind, name, location, detail, cost})
+    r.MonthlyCost[kind] += cost
+    r.TotalCost += cost
+}
+
+/* getWaste finds the unattached disks, the instances stopped for more
+ * than Arg1 days (default 30), and the snapshots and unused images older
+ * than the retention (default 90 days). The unused network resources,
+ * including the reserved addresses, are found by the network waste target.
+ */
+func (n *Compute) getWaste(stoppedDays, retentionDays string) (string, error) {
+    report := newWasteReport()
+    report.StoppedDays = WASTE_STOPPED_DAYS
+    report.RetentionDays = WASTE_RETENTION_DAYS
+    if stoppedDays != "" {
+        days, err := strconv.Atoi(stoppedDays)
+        if err != nil {
+            return fmt.Sprintf("invalid number of days: "), err
+        }
+        report.StoppedDays = days
+    }
+    if retentionDays != "" {
+        days, err := strconv.Atoi(retentionDays)
+        if err != nil {
+            return fmt.Sprintf("invalid retention: "), err
+        }
+        report.RetentionDays = days
+    }
+    prices, err := loadPriceTable()
+    if err != nil {
+        return fmt.Sprintf("failed to load price catalog: "), err
+    }
+    now := time.Now()
+
+    disks := map[string]*compute.Disk{}
+    usedImages := map[string]bool{}
+    err = n.client.Disks.AggregatedList(n.Project).Pages(n.context, func(list *compute.DiskAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, d := range scoped.Disks {
+                disks[d.SelfLink] = d
+                if d.SourceImage != "" {
+                    usedImages[imageSelfLink(n.Project, d.SourceImage)] = true
+                }
+                if len(d.Users) == 0 {
+                    detail := fmt.Sprintf("%s %dGB", lastPathElement(d.Type), d.SizeGb)
+                    if d.LastDetachTimestamp != "" {
+                        detail = detail + ", detached " + d.LastDetachTimestamp
+                    }
+                    location := diskLocation(d)
+                    report.add("disk", d.Name, location, detail, prices.diskMonthlyCost(d) * prices.regionMultiplier(location))
+                }
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list disks: "), err
+    }
+
+    // Instances have no stop time: use the end of their last stop operation.
+    // Instances stopped before the operations history are skipped, as we
+    // cannot tell for how long they have been stopped.
+    stopped, err := n.listStopTimes()
+    if err != nil {
+        return fmt.Sprintf("failed to list operations: "), err
+    }
+    err = n.client.Instances.AggregatedList(n.Project).Pages(n.context, func(list *compute.InstanceAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, i := range scoped.Instances {
+                if i.Status != "TERMINATED" {
+                    continue
+                }
+                t, ok := stopped[i.SelfLink]
+                if !ok || now.Sub(t) < time.Duration(report.StoppedDays) * 24 * time.Hour {
+                    continue
+                }
+                detail := "stopped since " + t.Format(time.RFC3339)
+                // A stopped instance only costs its disks.
+                cost := 0.0
+                for _, ad := range i.Disks {
+                    if d, ok := disks[ad.Source]; ok {
+                        cost += prices.diskMonthlyCost(d) * prices.regionMultiplier(diskLocation(d))
+                    }
+                }
+                report.add("instance", i.Name, lastPathElement(i.Zone), detail, cost)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list instances: "), err
+    }
+
+    retention := time.Duration(report.RetentionDays) * 24 * time.Hour
+    err = n.client.Snapshots.List(n.Project).Pages(n.context, func(list *compute.SnapshotList) error {
+        for _, s := range list.Items {
+            created, err := time.Parse(time.RFC3339, s.CreationTimestamp)
+            if err != nil || now.Sub(created) < retention {
+                continue
+            }
+            report.add("snapshot", s.Name, SCOPE_GLOBAL, "created " + s.CreationTimestamp,
+                prices.SnapshotGbMonth * bytesToGb(s.StorageBytes))
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list snapshots: "), err
+    }
+
+    err = n.client.InstanceTemplates.List(n.Project).Pages(n.context, func(list *compute.InstanceTemplateList) error {
+        for _, t := range list.Items {
+            if t.Properties == nil {
+                continue
+            }
+            for _, d := range t.Properties.Disks {
+                if d.InitializeParams != nil && d.InitializeParams.SourceImage != "" {
+                    usedImages[imageSelfLink(n.Project, d.InitializeParams.SourceImage)] = true
+                }
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list instance templates: "), err
+    }
+    var images []*compute.Image
+    err = n.client.Images.List(n.Project).Pages(n.context, func(list *compute.ImageList) error {
+        images = append(images, list.Items...)
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list images: "), err
+    }
+    for _, i := range unusedImages(n.Project, images, usedImages) {
+        created, err := time.Parse(time.RFC3339, i.CreationTimestamp)
+        if err != nil || now.Sub(created) < retention {
+            continue
+        }
+        report.add("image", i.Name, SCOPE_GLOBAL, "created " + i.CreationTimestamp,
+            prices.ImageGbMonth * bytesToGb(i.ArchiveSizeBytes))
+    }
+
+    if err := n.emitWaste(report); err != nil {
+        return fmt.Sprintf("failed to emit waste metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(report, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* imageSelfLink returns the full url of the image reference source, which
+ * can be a url, a partial path ("projects/p/global/images/i" or
+ * "global/images/i") or an image name of project. Image families
+ * ("global/images/family/f") keep their family path.
+ */
+func imageSelfLink(project, source string) string {
+    switch {
+    case strings.HasPrefix(source, COMPUTE_API_PREFIX):
+        return source
+    case strings.HasPrefix(source, "https://"):
+        // Other endpoints of the API, eg- https://compute.googleapis.com/compute/v1/.
+        if i := strings.Index(source, "/projects/"); i >= 0 {
+            return COMPUTE_API_PREFIX + source[i + 1:]
+        }
+        return source
+    case strings.HasPrefix(source, "projects/"):
+        return COMPUTE_API_PREFIX + source
+    case strings.HasPrefix(source, "global/"):
+        return COMPUTE_API_PREFIX + "projects/" + project + "/" + source
+    }
+    return COMPUTE_API_PREFIX + "projects/" + project + "/global/images/" + source
+}
+
+/* unusedImages returns the images of project that no disk or instance
+ * template uses. used holds the image urls from imageSelfLink. A family
+ * reference uses the newest image of the family that is not deprecated.
+ */
+func unusedImages(project string, images []*compute.Image, used map[string]bool) []*compute.Image {
+    newest := map[string]*compute.Image{}
+    for _, i := range images {
+        if i.Family == "" || (i.Deprecated != nil && i.Deprecated.State != "" && i.Deprecated.State != "ACTIVE") {
+            continue
+        }
+        if cur, ok := newest[i.Family]; !ok || i.CreationTimestamp > cur.CreationTimestamp {
+            newest[i.Family] = i
+        }
+    }
+    for family, i := range newest {
+        if used[imageSelfLink(project, "global/images/family/" + family)] {
+            used[imageSelfLink(project, i.SelfLink)] = true
+        }
+    }
+
+    var res []*compute.Image
+    for _, i := range images {
+        if !used[imageSelfLink(project, i.SelfLink)] {
+            res = append(res, i)
+        }
+    }
+    return res
+}
+
+/* listStopTimes returns the end time of the last stop operation of every
+ * instance still in the operations history.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/globalOperations/aggregatedList
+ */
+func (n *Compute) listStopTimes() (map[string]time.Time, error) {
+    res := map[string]time.Time{}
+    err := n.client.GlobalOperations.AggregatedList(n.Project).Filter(`operationType = "stop"`).Pages(n.context, func(list *compute.OperationAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, op := range scoped.Operations {
+                end, err := time.Parse(time.RFC3339, op.EndTime)
+                if err != nil {
+                    continue
+                }
+                if end.After(res[op.TargetLink]) {
+                    res[op.TargetLink] = end
+                }
+            }
+        }
+        return nil
+    })
+    return res, err
+}
+
+/* emitWaste sends the number and estimated monthly cost of the unused
+ * resources of every kind as metrics.
+ */
+func (n *Compute) emitWaste(report wasteReport) error {
+    if !n.EnableEmitter {
+        return nil
+    }
+
+    return emitCollectors(n.emitter, "compute.waste", n.Project, newWasteCollectors(n.Project, report, "disk", "instance", "snapshot", "image")...)
+}
+
+/* newWasteCollectors returns the number and estimated monthly cost of the
+ * unused resources of every kind, and their total cost.
+ */
+func newWasteCollectors(project string, report wasteReport, kinds ...string) []prometheus.Collector {
+    count := newGaugeVec("gcp_waste_resources", "Number of unused resources.", "project", "kind")
+    cost := newGaugeVec("gcp_waste_monthly_cost_dollars", "Estimated monthly cost of the unused resources.", "project", "kind")
+    total := newGaugeVec("gcp_waste_total_monthly_cost_dollars", "Estimated monthly cost of all the unused resources.", "project")
+
+    for _, kind := range kinds {
+        count.WithLabelValues(project, kind).Set(0)
+        cost.WithLabelValues(project, kind).Set(report.MonthlyCost[kind])
+    }
+    for _, f := range report.Findings {
+        count.WithLabelValues(project, f.Kind).Inc()
+    }
+    total.WithLabelValues(project).Set(report.TotalCost)
+
+    return []prometheus.Collector{count, cost, total}
+}
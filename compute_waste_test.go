@@ -0,0 +1,67 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestImageSelfLink(t *testing.T) {
+    want := COMPUTE_API_PREFIX + "projects/p/global/images/i"
+    tests := []struct {
+        source  string
+        want    string
+    }{
+        {want, want},
+        {"https://compute.googleapis.com/compute/v1/projects/p/global/images/i", want},
+        {"projects/p/global/images/i", want},
+        {"global/images/i", want},
+        {"i", want},
+        {"projects/debian-cloud/global/images/family/debian-10", COMPUTE_API_PREFIX + "projects/debian-cloud/global/images/family/debian-10"},
+        {"global/images/family/web", COMPUTE_API_PREFIX + "projects/p/global/images/family/web"},
+    }
+    for _, tt := range tests {
+        if got := imageSelfLink("p", tt.source); got != tt.want {
+            t.Errorf("imageSelfLink(%s) = %s, want %s", tt.source, got, tt.want)
+        }
+    }
+}
+
+func TestUnusedImages(t *testing.T) {
+    image := func(name, family, created, deprecated string) *compute.Image {
+        i := &compute.Image{Name: name, Family: family, CreationTimestamp: created,
+            SelfLink: COMPUTE_API_PREFIX + "projects/p/global/images/" + name}
+        if deprecated != "" {
+            i.Deprecated = &compute.DeprecationStatus{State: deprecated}
+        }
+        return i
+    }
+    images := []*compute.Image{
+        image("used", "", "2020-01-01T00:00:00Z", ""),
+        image("unused", "", "2020-01-01T00:00:00Z", ""),
+        image("web-1", "web", "2020-01-01T00:00:00Z", ""),
+        image("web-2", "web", "2020-02-01T00:00:00Z", ""),
+        image("web-3", "web", "2020-03-01T00:00:00Z", "DEPRECATED"),
+        image("db-1", "db", "2020-01-01T00:00:00Z", ""),
+    }
+    used := map[string]bool{
+        imageSelfLink("p", "global/images/used"):       true,
+        imageSelfLink("p", "global/images/family/web"): true,
+        // An image of the same name in another project is not ours.
+        imageSelfLink("p", "projects/other/global/images/db-1"): true,
+    }
+
+    var got []string
+    for _, i := range unusedImages("p", images, used) {
+        got = append(got, i.Name)
+    }
+    want := []string{"unused", "web-1", "web-3", "db-1"}
+    if len(got) != len(want) {
+        t.Fatalf("unusedImages() = %v, want %v", got, want)
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Errorf("unusedImages() = %v, want %v", got, want)
+        }
+    }
+}
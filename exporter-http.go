@@ -25,9 +25,11 @@ import (
 )
 
 var (
-    checkLen       validator.StringChainer
-    checkResource  validator.StringChainer
-    checkAction    validator.StringChainer
+    checkLen             validator.StringChainer
+    checkResource        validator.StringChainer
+    checkAction          validator.StringChainer
+    checkServiceAccount  validator.StringChainer
+    checkTags            validator.StringChainer
 )
 
 /*
@@ -36,6 +38,8 @@ func initialize() {
     checkLen      = validator.BuildStrChain().IsAlphaNum().IsMaxLen(REQUEST_MAX_LEN)
     checkResource = validator.BuildStrChain().IsAlphaNum().IsInList(QueryResources)
     checkAction   = validator.BuildStrChain().IsAlphaNum().IsInList(QueryActions)
+    checkServiceAccount = validator.BuildStrChain().IsAlphaNum().IsContains("@").IsMaxLen(SERVICE_ACCOUNT_MAX_LEN)
+    checkTags           = validator.BuildStrChain().IsAlphaNum().IsMaxLen(TAGS_MAX_LEN)
 }
 
 /* RunMetricsExporterHttp is the Cloud Function HTTP entry point.
@@ -89,7 +93,7 @@ func dispatch(ctx context.Context, w io.Writer, qry Query) {
         fmt.Fprintf(w, "%s", resp)
         health.Close()
     case "gke":
-        if checkLen.ValidateStr(qry.Namespace) == false {
+        if qry.Namespace != "-" && checkLen.ValidateStr(qry.Namespace) == false {
             fmt.Fprintf(w, "[debug] Namespace (%s) failed validations\n", qry.Namespace)
             return
         }
@@ -97,12 +101,26 @@ func dispatch(ctx context.Context, w io.Writer, qry Query) {
             fmt.Fprintf(w, "[debug] Target (%s) failed validations\n", qry.Target)
             return
         }
-        if checkLen.ValidateStr(qry.Zone) == false {
-            fmt.Fprintf(w, "[debug] Zone (%s) failed validations\n", qry.Zone)
+        if qry.Zone != "-" && checkLen.ValidateStr(qry.Zone) == false && checkLen.ValidateStr(qry.Region) == false {
+            fmt.Fprintf(w, "[debug] Both Zone (%s) and Region (%s) failed validations\n", qry.Zone, qry.Region)
             return
         }
 
-        gke, err := NewGKEBuilder().Context(ctx).Project(qry.Project).Zone(qry.Zone).Cluster(qry.Namespace).Arg1(qry.Arg1).Build()
+        if qry.KubeNamespace != "" && checkLen.ValidateStr(qry.KubeNamespace) == false {
+            fmt.Fprintf(w, "[debug] KubeNamespace (%s) failed validations\n", qry.KubeNamespace)
+            return
+        }
+        if qry.Selector != "" && checkLen.ValidateStr(qry.Selector) == false {
+            fmt.Fprintf(w, "[debug] Selector (%s) failed validations\n", qry.Selector)
+            return
+        }
+
+        bld := NewGKEBuilder().Context(ctx).Project(qry.Project).Zone(qry.Zone).Region(qry.Region).Cluster(qry.Namespace).Arg1(qry.Arg1).
+            KubeNamespace(qry.KubeNamespace).LabelSelector(qry.Selector)
+        if qry.Emit {
+            bld.EnableEmitter()
+        }
+        gke, err := bld.Build()
         if err != nil {
             fmt.Fprintf(w, "Error creating GKE client: %s\n", err)
             return
@@ -128,8 +146,31 @@ func dispatch(ctx context.Context, w io.Writer, qry Query) {
             fmt.Fprintf(w, "[debug] Region (%s) failed validations\n", qry.Region)
             return
         }
+        if qry.Instance != "" && checkLen.ValidateStr(qry.Zone) == false {
+            fmt.Fprintf(w, "[debug] Zone (%s) failed validations\n", qry.Zone)
+            return
+        }
+        for name, val := range map[string]string{"Instance": qry.Instance, "Direction": qry.Direction,
+            "Protocol": qry.Protocol, "Port": qry.Port, "Cidr": qry.Cidr} {
+            if val != "" && checkLen.ValidateStr(val) == false {
+                fmt.Fprintf(w, "[debug] %s (%s) failed validations\n", name, val)
+                return
+            }
+        }
+        if qry.Tags != "" && checkTags.ValidateStr(qry.Tags) == false {
+            fmt.Fprintf(w, "[debug] Tags (%s) failed validations\n", qry.Tags)
+            return
+        }
+        if qry.ServiceAccount != "" && checkServiceAccount.ValidateStr(qry.ServiceAccount) == false {
+            fmt.Fprintf(w, "[debug] ServiceAccount (%s) failed validations\n", qry.ServiceAccount)
+            return
+        }
 
-        net, err := NewNetworkBuilder().Context(ctx).Project(qry.Project).Region(qry.Region).Build()
+        bld := NewNetworkBuilder().Context(ctx).Project(qry.Project).Region(qry.Region).Zone(qry.Zone)
+        if qry.Emit {
+            bld.EnableEmitter()
+        }
+        net, err := bld.Build()
         if err != nil {
             fmt.Fprintf(w, "Error creating Compute client: %s\n", err)
             return
@@ -151,12 +192,28 @@ func dispatch(ctx context.Context, w io.Writer, qry Query) {
             fmt.Fprintf(w, "[debug] Target (%s) failed validations\n", qry.Target)
             return
         }
-        if checkLen.ValidateStr(qry.Region) == false && checkLen.ValidateStr(qry.Zone) == false {
+        if qry.Zone != "-" && checkLen.ValidateStr(qry.Region) == false && checkLen.ValidateStr(qry.Zone) == false {
             fmt.Fprintf(w, "[debug] Both Zone (%s) and Region (%s) failed validations\n", qry.Zone, qry.Region)
             return
         }
+        if qry.Retention != "" && checkLen.ValidateStr(qry.Retention) == false {
+            fmt.Fprintf(w, "[debug] Retention (%s) failed validations\n", qry.Retention)
+            return
+        }
+        if qry.OperationType != "" && checkLen.ValidateStr(qry.OperationType) == false {
+            fmt.Fprintf(w, "[debug] OperationType (%s) failed validations\n", qry.OperationType)
+            return
+        }
+        if qry.Label != "" && checkLen.ValidateStr(qry.Label) == false {
+            fmt.Fprintf(w, "[debug] Label (%s) failed validations\n", qry.Label)
+            return
+        }
 
-        comp, err := NewComputeBuilder().Context(ctx).Project(qry.Project).Region(qry.Region).Zone(qry.Zone).Build()
+        bld := NewComputeBuilder().Context(ctx).Project(qry.Project).Region(qry.Region).Zone(qry.Zone)
+        if qry.Emit {
+            bld.EnableEmitter()
+        }
+        comp, err := bld.Build()
         if err != nil {
             fmt.Fprintf(w, "Error creating Compute client: %s\n", err)
             return
@@ -209,7 +266,20 @@ func debug(w io.Writer, qry Query) {
     fmt.Fprintf(w, "[Debug] Namespace = %s\n", html.EscapeString(qry.Namespace))
     fmt.Fprintf(w, "[Debug] Target = %s\n", html.EscapeString(qry.Target))
     fmt.Fprintf(w, "[Debug] Arg1 = %s\n", html.EscapeString(qry.Arg1))
+    fmt.Fprintf(w, "[Debug] KubeNamespace = %s\n", html.EscapeString(qry.KubeNamespace))
+    fmt.Fprintf(w, "[Debug] Selector = %s\n", html.EscapeString(qry.Selector))
+    fmt.Fprintf(w, "[Debug] Instance = %s\n", html.EscapeString(qry.Instance))
+    fmt.Fprintf(w, "[Debug] Tags = %s\n", html.EscapeString(qry.Tags))
+    fmt.Fprintf(w, "[Debug] ServiceAccount = %s\n", html.EscapeString(qry.ServiceAccount))
+    fmt.Fprintf(w, "[Debug] Direction = %s\n", html.EscapeString(qry.Direction))
+    fmt.Fprintf(w, "[Debug] Protocol = %s\n", html.EscapeString(qry.Protocol))
+    fmt.Fprintf(w, "[Debug] Port = %s\n", html.EscapeString(qry.Port))
+    fmt.Fprintf(w, "[Debug] Cidr = %s\n", html.EscapeString(qry.Cidr))
+    fmt.Fprintf(w, "[Debug] Retention = %s\n", html.EscapeString(qry.Retention))
+    fmt.Fprintf(w, "[Debug] OperationType = %s\n", html.EscapeString(qry.OperationType))
+    fmt.Fprintf(w, "[Debug] Label = %s\n", html.EscapeString(qry.Label))
     fmt.Fprintf(w, "[Debug] Zone = %s\n", html.EscapeString(qry.Zone))
     fmt.Fprintf(w, "[Debug] Region = %s\n", html.EscapeString(qry.Region))
+    fmt.Fprintf(w, "[Debug] Emit = %t\n", qry.Emit)
 }
 
@@ -0,0 +1,280 @@
+package metricsexporter
+/**
+ * Pod and service IP capacity planning for VPC-native GKE clusters.
+ *
+ * Every node reserves a slice of the cluster pod range that is at least
+ * twice as large as its max pods per node, so the pod range puts a hard
+ * ceiling on the number of nodes a cluster can grow to.
+ *
+ * The node count of a pool is the target size of its managed instance
+ * groups. The services range usage is the number of services with a
+ * cluster IP, read from the API server of the cluster.
+ *
+ * @see https://cloud.google.com/kubernetes-engine/docs/how-to/flexible-pod-cidr
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "net"
+    "strings"
+
+    compute  "google.golang.org/api/compute/v1"
+    gke      "google.golang.org/api/container/v1"
+)
+
+const (
+    GKE_DEFAULT_MAX_PODS_PER_NODE = 110
+)
+
+/* IP capacity of a single node pool.
+ */
+type gkeNodePoolIpCapacity struct {
+    Name            string  `json:"name"`
+    MaxPodsPerNode  int64   `json:"maxPodsPerNode"`
+    PodCidrSize     int     `json:"podCidrSize"`
+    Nodes           int64   `json:"nodes"`
+    MaxNodes        int64   `json:"autoscalingMaxNodes,omitempty"`
+    PodAddresses    int64   `json:"podAddresses"`
+}
+
+/* IP capacity of a cluster.
+ */
+type gkeIpCapacity struct {
+    Cluster            string                   `json:"cluster"`
+    Location           string                   `json:"location"`
+    VpcNative          bool                     `json:"vpcNative"`
+    PodRange           string                   `json:"podRange"`
+    PodRangeSize       int64                    `json:"podRangeSize"`
+    PodRangeUsed       int64                    `json:"podRangeUsed"`
+    MaxPodsPerNode     int64                    `json:"maxPodsPerNode"`
+    MaxNodes           int64                    `json:"maxNodes"`
+    CurrentNodes       int64                    `json:"currentNodes"`
+    NodeHeadroom       int64                    `json:"nodeHeadroom"`
+    ServicesRange      string                   `json:"servicesRange"`
+    ServicesRangeSize  int64                    `json:"servicesRangeSize"`
+    ServicesRangeUsed  int64                    `json:"servicesRangeUsed"`
+    ServicesError      string                   `json:"servicesError,omitempty"`
+    NodePools          []gkeNodePoolIpCapacity  `json:"nodePools"`
+}
+
+/* getIpCapacityList reports the ip capacity of every cluster in the location.
+ * Clusters whose API server cannot be reached are reported with a
+ * services error instead of failing the whole request.
+ * @see https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.clusters/list
+ */
+func (g *GKE) getIpCapacityList() (string, error) {
+    clusters, err := g.listClusters()
+    if err != nil {
+        return fmt.Sprintf("failed to list clusters: "), err
+    }
+    svc, err := compute.NewService(g.context)
+    if err != nil {
+        return fmt.Sprintf("failed to create compute client: "), err
+    }
+
+    var caps []gkeIpCapacity
+    for _, c := range clusters {
+        nodes, err := g.listNodePoolSizes(svc, c)
+        if err != nil {
+            return fmt.Sprintf("failed to get the node pools size of cluster %s: ", c.Name), err
+        }
+        capacity := newGkeIpCapacity(c, nodes)
+        if used, err := g.countServiceIps(c); err != nil {
+            capacity.ServicesError = err.Error()
+        } else {
+            capacity.ServicesRangeUsed = used
+        }
+        caps = append(caps, capacity)
+    }
+
+    if err := g.emitIpCapacity(caps); err != nil {
+        return fmt.Sprintf("failed to emit ip capacity metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(caps, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* listNodePoolSizes returns the number of nodes of every node pool of
+ * cluster c: the sum of the target sizes of its instance groups.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/instanceGroupManagers/get
+ */
+func (g *GKE) listNodePoolSizes(svc *compute.Service, c *gke.Cluster) (map[string]int64, error) {
+    res := map[string]int64{}
+    for _, np := range c.NodePools {
+        for _, link := range np.InstanceGroupUrls {
+            project, zone, name := instanceGroupManagerPath(link)
+            mig, err := svc.InstanceGroupManagers.Get(project, zone, name).Context(g.context).Do()
+            if err != nil {
+                return nil, err
+            }
+            res[np.Name] += mig.TargetSize
+        }
+    }
+    return res, nil
+}
+
+/* countServiceIps returns the number of services of cluster c that were
+ * given an address of the services range.
+ */
+func (g *GKE) countServiceIps(c *gke.Cluster) (int64, error) {
+    kube, err := g.newClusterKubeClient(c)
+    if err != nil {
+        return 0, err
+    }
+    services, err := kube.listServices("")
+    if err != nil {
+        return 0, err
+    }
+    var res int64
+    for _, s := range services {
+        // Headless and ExternalName services have no cluster IP.
+        if s.Spec.ClusterIP != "" && s.Spec.ClusterIP != "None" {
+            res++
+        }
+    }
+    return res, nil
+}
+
+/* instanceGroupManagerPath returns the project, zone and name of the
+ * instance group manager link.
+ */
+func instanceGroupManagerPath(link string) (string, string, string) {
+    project, zone := "", ""
+    parts := strings.Split(link, "/")
+    for i := 0; i < len(parts) - 1; i++ {
+        if parts[i] == "projects" {
+            project = parts[i + 1]
+        } else if parts[i] == "zones" {
+            zone = parts[i + 1]
+        }
+    }
+    return project, zone, parts[len(parts) - 1]
+}
+
+/* newGkeIpCapacity works out how many nodes the pod range of
+ * cluster c can hold and how much of it is already allocated.
+ * nodes is the number of nodes of every node pool.
+ */
+func newGkeIpCapacity(c *gke.Cluster, nodes map[string]int64) gkeIpCapacity {
+    res := gkeIpCapacity{
+        Cluster:       c.Name,
+        Location:      c.Location,
+        PodRange:      c.ClusterIpv4Cidr,
+        ServicesRange: c.ServicesIpv4Cidr,
+        CurrentNodes:  c.CurrentNodeCount,
+    }
+    if p := c.IpAllocationPolicy; p != nil {
+        res.VpcNative = p.UseIpAliases
+        if p.ClusterIpv4CidrBlock != "" {
+            res.PodRange = p.ClusterIpv4CidrBlock
+        }
+        if p.ServicesIpv4CidrBlock != "" {
+            res.ServicesRange = p.ServicesIpv4CidrBlock
+        }
+    }
+    res.PodRangeSize = cidrSize(res.PodRange)
+    res.ServicesRangeSize = cidrSize(res.ServicesRange)
+
+    res.MaxPodsPerNode = GKE_DEFAULT_MAX_PODS_PER_NODE
+    if c.DefaultMaxPodsConstraint != nil && c.DefaultMaxPodsConstraint.MaxPodsPerNode > 0 {
+        res.MaxPodsPerNode = c.DefaultMaxPodsConstraint.MaxPodsPerNode
+    }
+    defaultCidrSize := podCidrSize(res.MaxPodsPerNode)
+    if c.NodeIpv4CidrSize > 0 {
+        defaultCidrSize = int(c.NodeIpv4CidrSize)
+    }
+
+    // Autoscaling limits apply to every zone of the node pool, which
+    // defaults to the zones of the cluster.
+    zones := int64(len(c.Locations))
+    if zones == 0 {
+        zones = 1
+    }
+    for _, np := range c.NodePools {
+        pool := gkeNodePoolIpCapacity{
+            Name:           np.Name,
+            MaxPodsPerNode: res.MaxPodsPerNode,
+            PodCidrSize:    defaultCidrSize,
+            Nodes:          nodes[np.Name],
+        }
+        if np.MaxPodsConstraint != nil && np.MaxPodsConstraint.MaxPodsPerNode > 0 {
+            pool.MaxPodsPerNode = np.MaxPodsConstraint.MaxPodsPerNode
+            pool.PodCidrSize = podCidrSize(pool.MaxPodsPerNode)
+        }
+        if np.Autoscaling != nil && np.Autoscaling.Enabled {
+            poolZones := int64(len(np.Locations))
+            if poolZones == 0 {
+                poolZones = zones
+            }
+            pool.MaxNodes = np.Autoscaling.MaxNodeCount * poolZones
+        }
+        pool.PodAddresses = pool.Nodes * (int64(1) << uint(32 - pool.PodCidrSize))
+        res.PodRangeUsed += pool.PodAddresses
+        res.NodePools = append(res.NodePools, pool)
+    }
+
+    block := int64(1) << uint(32 - defaultCidrSize)
+    res.MaxNodes = res.PodRangeSize / block
+    if res.PodRangeSize > res.PodRangeUsed {
+        res.NodeHeadroom = (res.PodRangeSize - res.PodRangeUsed) / block
+    }
+    return res
+}
+
+/* emitIpCapacity sends the ip capacity of every cluster as metrics.
+ */
+func (g *GKE) emitIpCapacity(caps []gkeIpCapacity) error {
+    if !g.EnableEmitter {
+        return nil
+    }
+
+    labels := []string{"project", "location", "cluster"}
+    maxNodes := newGaugeVec("gcp_gke_pod_range_max_nodes", "Maximum number of nodes the cluster pod range supports.", labels...)
+    headroom := newGaugeVec("gcp_gke_pod_range_node_headroom", "Number of nodes that can still be added before the pod range is exhausted.", labels...)
+    podUsed := newGaugeVec("gcp_gke_pod_range_used_ratio", "Ratio of the cluster pod range allocated to nodes.", labels...)
+    svcSize := newGaugeVec("gcp_gke_services_range_size", "Number of addresses in the cluster services range.", labels...)
+    svcUsed := newGaugeVec("gcp_gke_services_range_used_ratio", "Ratio of the cluster services range allocated to services.", labels...)
+
+    for _, c := range caps {
+        l := []string{g.Project, c.Location, c.Cluster}
+        maxNodes.WithLabelValues(l...).Set(float64(c.MaxNodes))
+        headroom.WithLabelValues(l...).Set(float64(c.NodeHeadroom))
+        if c.PodRangeSize > 0 {
+            podUsed.WithLabelValues(l...).Set(float64(c.PodRangeUsed) / float64(c.PodRangeSize))
+        }
+        svcSize.WithLabelValues(l...).Set(float64(c.ServicesRangeSize))
+        if c.ServicesError == "" && c.ServicesRangeSize > 0 {
+            svcUsed.WithLabelValues(l...).Set(float64(c.ServicesRangeUsed) / float64(c.ServicesRangeSize))
+        }
+    }
+
+    return emitCollectors(g.emitter, g.emitTarget("gke.ipcapacity.list"), g.Project, maxNodes, headroom, podUsed, svcSize, svcUsed)
+}
+
+/* podCidrSize returns the prefix length of the range GKE reserves
+ * on each node for maxPods pods.
+ */
+func podCidrSize(maxPods int64) int {
+    size := 32
+    for int64(1) << uint(32 - size) < 2 * maxPods && size > 0 {
+        size--
+    }
+    return size
+}
+
+/* cidrSize returns the number of IPv4 addresses in cidr, or 0 if
+ * cidr cannot be parsed.
+ */
+func cidrSize(cidr string) int64 {
+    _, ipnet, err := net.ParseCIDR(cidr)
+    if err != nil {
+        return 0
+    }
+    ones, bits := ipnet.Mask.Size()
+    return int64(1) << uint(bits - ones)
+}
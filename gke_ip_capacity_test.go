@@ -0,0 +1,85 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    gke  "google.golang.org/api/container/v1"
+)
+
+func TestPodCidrSize(t *testing.T) {
+    tests := []struct {
+        maxPods  int64
+        want     int
+    }{
+        {0, 32},
+        {8, 28},
+        {16, 27},
+        {32, 26},
+        {64, 25},
+        {110, 24},
+        {128, 24},
+        {256, 23},
+    }
+    for _, tt := range tests {
+        if got := podCidrSize(tt.maxPods); got != tt.want {
+            t.Errorf("podCidrSize(%d) = %d, want %d", tt.maxPods, got, tt.want)
+        }
+    }
+}
+
+func TestInstanceGroupManagerPath(t *testing.T) {
+    link := "https://www.googleapis.com/compute/v1/projects/my-project/zones/us-central1-a/instanceGroupManagers/gke-cluster-pool-1234-grp"
+    project, zone, name := instanceGroupManagerPath(link)
+    if project != "my-project" || zone != "us-central1-a" || name != "gke-cluster-pool-1234-grp" {
+        t.Errorf("instanceGroupManagerPath() = %s, %s, %s", project, zone, name)
+    }
+}
+
+func TestNewGkeIpCapacity(t *testing.T) {
+    c := &gke.Cluster{
+        Name:               "cluster",
+        Location:           "us-central1",
+        Locations:          []string{"us-central1-a", "us-central1-b"},
+        IpAllocationPolicy: &gke.IPAllocationPolicy{
+            UseIpAliases:          true,
+            ClusterIpv4CidrBlock:  "10.0.0.0/20",
+            ServicesIpv4CidrBlock: "10.1.0.0/24",
+        },
+        NodePools: []*gke.NodePool{
+            {
+                Name:             "default-pool",
+                InitialNodeCount: 3,
+                Autoscaling:      &gke.NodePoolAutoscaling{Enabled: true, MaxNodeCount: 5},
+            },
+            {
+                Name:              "small-pool",
+                InitialNodeCount:  1,
+                Locations:         []string{"us-central1-a"},
+                MaxPodsConstraint: &gke.MaxPodsConstraint{MaxPodsPerNode: 32},
+                Autoscaling:       &gke.NodePoolAutoscaling{Enabled: true, MaxNodeCount: 4},
+            },
+        },
+    }
+    res := newGkeIpCapacity(c, map[string]int64{"default-pool": 4, "small-pool": 2})
+
+    if res.PodRangeSize != 4096 || res.ServicesRangeSize != 256 {
+        t.Errorf("range sizes = %d, %d, want 4096, 256", res.PodRangeSize, res.ServicesRangeSize)
+    }
+    if len(res.NodePools) != 2 {
+        t.Fatalf("got %d node pools, want 2", len(res.NodePools))
+    }
+    // The node counts are the ones of the instance groups, not initialNodeCount.
+    def, small := res.NodePools[0], res.NodePools[1]
+    if def.Nodes != 4 || def.PodCidrSize != 24 || def.PodAddresses != 4 * 256 || def.MaxNodes != 10 {
+        t.Errorf("default-pool = %+v", def)
+    }
+    if small.Nodes != 2 || small.PodCidrSize != 26 || small.PodAddresses != 2 * 64 || small.MaxNodes != 4 {
+        t.Errorf("small-pool = %+v", small)
+    }
+    if res.PodRangeUsed != 1024 + 128 {
+        t.Errorf("PodRangeUsed = %d, want %d", res.PodRangeUsed, 1024 + 128)
+    }
+    if res.MaxNodes != 16 || res.NodeHeadroom != (4096 - 1152) / 256 {
+        t.Errorf("MaxNodes, NodeHeadroom = %d, %d", res.MaxNodes, res.NodeHeadroom)
+    }
+}
@@ -0,0 +1,146 @@
+package metricsexporter
+/**
+ * History of the operations (upgrades, resizes, repairs, ...) run on GKE clusters.
+ *
+ * @see https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.operations/list
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+
+    gke  "google.golang.org/api/container/v1"
+)
+
+/* Cluster operation.
+ */
+type gkeOperation struct {
+    Name           string    `json:"name"`
+    Type           string    `json:"type"`
+    Status         string    `json:"status"`
+    Cluster        string    `json:"cluster"`
+    Location       string    `json:"location"`
+    StartTime      string    `json:"startTime"`
+    EndTime        string    `json:"endTime,omitempty"`
+    Duration       float64   `json:"durationSeconds"`
+    Failed         bool      `json:"failed"`
+    Conditions     []string  `json:"conditions,omitempty"`
+    StatusMessage  string    `json:"statusMessage,omitempty"`
+}
+
+/* getOperationsList lists the operations in the location. Set the cluster
+ * to "-" to include the operations of all clusters, and Arg1 to a duration
+ * (eg- "24h") to only include operations started within that time window.
+ *
+ * The list call has no filter, so the cluster and the time window are
+ * filtered client-side from every operation still in the history.
+ * Operations without a valid start time are left out of a time window.
+ */
+func (g *GKE) getOperationsList() (string, error) {
+    var since time.Time
+    if g.Arg1 != "" {
+        window, err := time.ParseDuration(g.Arg1)
+        if err != nil {
+            return fmt.Sprintf("invalid time window: "), err
+        }
+        since = time.Now().Add(-window)
+    }
+
+    list, err := g.client.Projects.Locations.Operations.List(g.locationPath()).Do()
+    if err != nil {
+        return fmt.Sprintf("failed to list operations: "), err
+    }
+
+    res := []gkeOperation{}
+    for _, op := range list.Operations {
+        o := gkeOperation{
+            Name:          op.Name,
+            Type:          op.OperationType,
+            Status:        op.Status,
+            Cluster:       operationCluster(op.TargetLink),
+            Location:      op.Location,
+            StartTime:     op.StartTime,
+            EndTime:       op.EndTime,
+            StatusMessage: op.StatusMessage,
+        }
+        if o.Location == "" {
+            o.Location = op.Zone
+        }
+        if g.Cluster != "-" && o.Cluster != g.Cluster {
+            continue
+        }
+
+        start, err := time.Parse(time.RFC3339, op.StartTime)
+        if !since.IsZero() && (err != nil || start.Before(since)) {
+            continue
+        }
+        end := time.Now()
+        if t, e := time.Parse(time.RFC3339, op.EndTime); e == nil {
+            end = t
+        }
+        if err == nil {
+            o.Duration = end.Sub(start).Seconds()
+        }
+        o.Conditions = operationConditions(op)
+        o.Failed = op.Status == "ABORTING" || (op.Status == "DONE" && len(o.Conditions) > 0)
+        res = append(res, o)
+    }
+
+    if err := g.emitOperations(res); err != nil {
+        return fmt.Sprintf("failed to emit operation metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* operationConditions returns the codes of the cluster and node pool
+ * conditions of op. The v1 Operation has no error field, and GKE only sets
+ * these conditions to describe what went wrong (eg- GCE_STOCKOUT), so a
+ * done operation with conditions failed.
+ */
+func operationConditions(op *gke.Operation) []string {
+    var res []string
+    for _, c := range append(op.ClusterConditions, op.NodepoolConditions...) {
+        res = append(res, c.Code)
+    }
+    return res
+}
+
+/* operationCluster returns the name of the cluster in the target link of an
+ * operation, eg- ".../clusters/my-cluster/nodePools/default-pool".
+ */
+func operationCluster(target string) string {
+    parts := strings.Split(target, "/")
+    for i := 0; i < len(parts) - 1; i++ {
+        if parts[i] == "clusters" {
+            return parts[i + 1]
+        }
+    }
+    return ""
+}
+
+/* emitOperations sends the number of running and failed operations per cluster as metrics.
+ */
+func (g *GKE) emitOperations(ops []gkeOperation) error {
+    if !g.EnableEmitter {
+        return nil
+    }
+
+    count := newGaugeVec("gcp_gke_operations", "Number of running or failed cluster operations.", "project", "location", "cluster", "state")
+    for _, o := range ops {
+        if o.Status == "RUNNING" || o.Status == "PENDING" {
+            count.WithLabelValues(g.Project, o.Location, o.Cluster, "running").Inc()
+        }
+        if o.Failed {
+            count.WithLabelValues(g.Project, o.Location, o.Cluster, "failed").Inc()
+        }
+    }
+
+    return emitCollectors(g.emitter, g.emitTarget("gke.operations.list"), g.Project, count)
+}
@@ -0,0 +1,49 @@
+package metricsexporter
+
+import (
+    "reflect"
+    "testing"
+
+    gke  "google.golang.org/api/container/v1"
+)
+
+func TestOperationConditions(t *testing.T) {
+    tests := []struct {
+        name  string
+        op    *gke.Operation
+        want  []string
+    }{
+        {"no conditions", &gke.Operation{Status: "DONE", StatusMessage: "done"}, nil},
+        {
+            "cluster and node pool conditions",
+            &gke.Operation{
+                ClusterConditions:  []*gke.StatusCondition{{Code: "GCE_QUOTA_EXCEEDED"}},
+                NodepoolConditions: []*gke.StatusCondition{{Code: "GCE_STOCKOUT"}},
+            },
+            []string{"GCE_QUOTA_EXCEEDED", "GCE_STOCKOUT"},
+        },
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            if got := operationConditions(tt.op); !reflect.DeepEqual(got, tt.want) {
+                t.Errorf("got %v, want %v", got, tt.want)
+            }
+        })
+    }
+}
+
+func TestOperationCluster(t *testing.T) {
+    tests := []struct {
+        target  string
+        want    string
+    }{
+        {"https://container.googleapis.com/v1/projects/p/locations/us-central1/clusters/c1", "c1"},
+        {"https://container.googleapis.com/v1/projects/p/locations/us-central1/clusters/c1/nodePools/np", "c1"},
+        {"https://container.googleapis.com/v1/projects/p/locations/us-central1", ""},
+    }
+    for _, tt := range tests {
+        if got := operationCluster(tt.target); got != tt.want {
+            t.Errorf("operationCluster(%q) = %q, want %q", tt.target, got, tt.want)
+        }
+    }
+}
@@ -8,6 +8,7 @@ package metricsexporter
 import (
     "fmt"
     "context"
+    "encoding/base64"
     "encoding/json"
 
     oauth2  "golang.org/x/oauth2/google"
@@ -38,7 +39,10 @@ type GKEBuilder interface {
     Project(string)           GKEBuilder
     Cluster(string)           GKEBuilder
     Zone(string)              GKEBuilder
+    Region(string)            GKEBuilder
     Arg1(string)              GKEBuilder
+    KubeNamespace(string)     GKEBuilder
+    LabelSelector(string)     GKEBuilder
     EnableEmitter()           GKEBuilder
 
     Build()                   (GKE, error)
@@ -50,7 +54,10 @@ type gkeBuild struct {
     project        string
     cluster        string
     zone           string
+    region         string
     arg1           string
+    kubenamespace  string
+    labelselector  string
     enableemitter  bool
 }
 
@@ -63,7 +70,10 @@ type GKE struct {
     Project        string
     Cluster        string
     Zone           string
+    Region         string
     Arg1           string
+    KubeNamespace  string
+    LabelSelector  string
     EnableEmitter  bool
     emitter        Emitters
 }
@@ -97,12 +107,21 @@ func (b *gkeBuild) Project(project string) GKEBuilder {
 }
 
 /* Zone is the GCP zone the resource resides in.
+ * Use "-" to query clusters in all locations.
  */
 func (b *gkeBuild) Zone(zone string) GKEBuilder {
 	b.zone = zone
 	return b
 }
 
+/* Region is the GCP region of a regional cluster.
+ * It is only used if no zone is set.
+ */
+func (b *gkeBuild) Region(region string) GKEBuilder {
+	b.region = region
+	return b
+}
+
 /* Arg1 is an optional argument passed into the requested resource,
  * action, and target. 
  * The argument type and value depend on the context of the request.
@@ -113,6 +132,21 @@ func (b *gkeBuild) Arg1(arg1 string) GKEBuilder {
 	return b
 }
 
+/* KubeNamespace is the Kubernetes namespace inside the cluster.
+ * Leave it empty to query all namespaces.
+ */
+func (b *gkeBuild) KubeNamespace(namespace string) GKEBuilder {
+	b.kubenamespace = namespace
+	return b
+}
+
+/* LabelSelector filters Kubernetes objects by label, eg- "app=web,tier!=db".
+ */
+func (b *gkeBuild) LabelSelector(selector string) GKEBuilder {
+	b.labelselector = selector
+	return b
+}
+
 /*  
  */
 func (b *gkeBuild) EnableEmitter() GKEBuilder {
@@ -144,12 +178,91 @@ func (b *gkeBuild) Build() (GKE, error) {
         Cluster:        b.cluster,
         Project:        b.project,
         Zone:           b.zone,
+        Region:         b.region,
         Arg1:           b.arg1,
+        KubeNamespace:  b.kubenamespace,
+        LabelSelector:  b.labelselector,
         EnableEmitter:  b.enableemitter,
         emitter:        pusher,
     }, nil
 }
 
+/* Location is the zone, region, or "-" (all locations) of the request.
+ */
+func (g *GKE) Location() string {
+    if g.Zone != "" {
+        return g.Zone
+    }
+    if g.Region != "" {
+        return g.Region
+    }
+    return "-"
+}
+
+/* emitTarget is the push target of the metrics of target for the location
+ * and cluster of the request ("-" for all), so that the metrics of a cluster
+ * do not replace the ones of another cluster or location.
+ */
+func (g *GKE) emitTarget(target string) string {
+    cluster := g.Cluster
+    if cluster == "" {
+        cluster = "-"
+    }
+    return target + "." + g.Location() + "." + cluster
+}
+
+/* locationPath is the resource name of the location of the request.
+ */
+func (g *GKE) locationPath() string {
+    return fmt.Sprintf("projects/%s/locations/%s", g.Project, g.Location())
+}
+
+/* listClusters lists the clusters in the location of the request.
+ * @see https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.clusters/list
+ */
+func (g *GKE) listClusters() ([]*gke.Cluster, error) {
+    list, err := g.client.Projects.Locations.Clusters.List(g.locationPath()).Do()
+    if err != nil {
+        return nil, err
+    }
+    return list.Clusters, nil
+}
+
+/* getCluster gets the cluster of the request. When querying all
+ * locations the cluster is looked up by name.
+ * @see https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.clusters/get
+ */
+func (g *GKE) getCluster() (*gke.Cluster, error) {
+    if g.Location() != "-" {
+        return g.client.Projects.Locations.Clusters.Get(g.locationPath() + "/clusters/" + g.Cluster).Do()
+    }
+
+    clusters, err := g.listClusters()
+    if err != nil {
+        return nil, err
+    }
+    for _, c := range clusters {
+        if c.Name == g.Cluster {
+            return c, nil
+        }
+    }
+    return nil, fmt.Errorf("cluster %s not found in any location", g.Cluster)
+}
+
+/* clusterPath is the resource name of the cluster of the request.
+ */
+func (g *GKE) clusterPath() (string, error) {
+    if g.Location() != "-" {
+        return g.locationPath() + "/clusters/" + g.Cluster, nil
+    }
+
+    c, err := g.getCluster()
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("projects/%s/locations/%s/clusters/%s", g.Project, c.Location, c.Name), nil
+}
+
 /* @TODO
  * Do acts on your request to retrieve and return a response to you.
  */
@@ -164,41 +277,113 @@ func (g *GKE) Do(qry Query) (string, error) {
         return g.getNodePoolsGet()
     }  else if qry.Resource == "gke" && qry.Action == "get" && qry.Target == "usablesubnets.list" {
         return g.getUsableSubnetsList()
+    } else if qry.Resource == "gke" && qry.Action == "get" && qry.Target == "ipcapacity.list" {
+        return g.getIpCapacityList()
+    } else if qry.Resource == "gke" && qry.Action == "get" && qry.Target == "workloads.summary" {
+        return g.getWorkloadsSummary()
+    } else if qry.Resource == "gke" && qry.Action == "get" && qry.Target == "security.audit" {
+        return g.getSecurityAudit()
+    } else if qry.Resource == "gke" && qry.Action == "get" && qry.Target == "versions.report" {
+        return g.getVersionsReport()
+    } else if qry.Resource == "gke" && qry.Action == "get" && qry.Target == "operations.list" {
+        return g.getOperationsList()
     }
     return "[Debug] It will call some GKE operations to return json response", nil
 }
 
-/* @TODO
+/* Pod as returned by the pods.list target.
+ */
+type gkePod struct {
+    Name       string  `json:"name"`
+    Namespace  string  `json:"namespace"`
+    Phase      string  `json:"phase"`
+    Node       string  `json:"node"`
+    Restarts   int32   `json:"restarts"`
+    Owner      string  `json:"owner,omitempty"`
+}
+
+/* newKubeClient connects to the API server of the cluster using
+ * the OAuth token of the function's service account.
+ */
+func (g *GKE) newKubeClient() (*kubeClient, error) {
+    cluster, err := g.getCluster()
+    if err != nil {
+        return nil, err
+    }
+    return g.newClusterKubeClient(cluster)
+}
+
+/* newClusterKubeClient connects to the API server of cluster.
+ */
+func (g *GKE) newClusterKubeClient(cluster *gke.Cluster) (*kubeClient, error) {
+    if cluster.MasterAuth == nil {
+        return nil, fmt.Errorf("cluster %s has no master auth", cluster.Name)
+    }
+    ca, err := base64.StdEncoding.DecodeString(cluster.MasterAuth.ClusterCaCertificate)
+    if err != nil {
+        return nil, err
+    }
+    ts, err := oauth2.DefaultTokenSource(g.context, gke.CloudPlatformScope)
+    if err != nil {
+        return nil, err
+    }
+    return newKubeClient(g.context, "https://" + cluster.Endpoint, ca, ts)
+}
+
+/* @see https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.16/#list-pod-v1-core
  */
 func (g *GKE) getPodsList() (string, error) {
-    /*
-    pods, err := g.client.GetPods(g.context)
+    kube, err := g.newKubeClient()
     if err != nil {
-        return fmt.Sprintf("Error, getPodsList()"), err
+        return fmt.Sprintf("failed to connect to cluster: "), err
     }
-    
-    var res string
-    for _, pod := range pods {
-        res = res + "Name:" + pod.ObjectMeta.Name
+    return listGkePods(kube, g.KubeNamespace, g.LabelSelector)
+}
+
+/* listGkePods returns the pods in namespace that match selector as json.
+ */
+func listGkePods(kube *kubeClient, namespace, selector string) (string, error) {
+    pods, err := kube.listPods(namespace, selector)
+    if err != nil {
+        return fmt.Sprintf("failed to list pods: "), err
+    }
+
+    res := []gkePod{}
+    for _, p := range pods {
+        pod := gkePod{
+            Name:      p.Metadata.Name,
+            Namespace: p.Metadata.Namespace,
+            Phase:     p.Status.Phase,
+            Node:      p.Spec.NodeName,
+        }
+        for _, cs := range p.Status.ContainerStatuses {
+            pod.Restarts += cs.RestartCount
+        }
+        for _, o := range p.Metadata.OwnerReferences {
+            if o.Controller {
+                pod.Owner = o.Kind + "/" + o.Name
+            }
+        }
+        res = append(res, pod)
     }
-    
-    return res, nil
-    */
 
-    return "[Debug] Feature not implemented yet", nil
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
 }
 
-/* @see https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.zones.clusters/list
+/* @see https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.clusters/list
  */
 func (g *GKE) getServicesList() (string, error) {
     var res string
-    var svc = g.client
-    
-    list, err := svc.Projects.Zones.Clusters.List(g.Project, g.Zone).Do()
+
+    clusters, err := g.listClusters()
 	if err != nil {
 		return fmt.Sprintf("failed to list clusters: "), err
 	}
-	for _, v := range list.Clusters {
+	for _, v := range clusters {
 		bt, err := v.MarshalJSON()
         if err != nil {
 			return "", err
@@ -211,13 +396,17 @@ func (g *GKE) getServicesList() (string, error) {
     return res, nil
 }
 
-/* @see https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.zones.clusters.nodePools/list
+/* @see https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations.clusters.nodePools/list
  */
 func (g *GKE) getNodePoolsList() (string, error) {
     var res string
     var svc = g.client
-    
-    list, err := svc.Projects.Zones.Clusters.NodePools.List(g.Project, g.Zone, g.Cluster).Do()
+
+    cluster, err := g.clusterPath()
+	if err != nil {
+		return fmt.Sprintf("failed to get cluster: "), err
+	}
+    list, err := svc.Projects.Locations.Clusters.NodePools.List(cluster).Do()
 	if err != nil {
 		return fmt.Sprintf("failed to list node pools: "), err
 	}
@@ -240,8 +429,12 @@ func (g *GKE) getNodePoolsGet() (string, error) {
     var res string
     var svc = g.client
     
-    // @see https://godoc.org/google.golang.org/api/container/v1#ProjectsZonesClustersNodePoolsService.Get
-    get, err := svc.Projects.Zones.Clusters.NodePools.Get(g.Project, g.Zone, g.Cluster, g.Arg1).Do()
+    cluster, err := g.clusterPath()
+	if err != nil {
+		return fmt.Sprintf("failed to get cluster: "), err
+	}
+    // @see https://godoc.org/google.golang.org/api/container/v1#ProjectsLocationsClustersNodePoolsService.Get
+    get, err := svc.Projects.Locations.Clusters.NodePools.Get(cluster + "/nodePools/" + g.Arg1).Do()
 	if err != nil {
 		return fmt.Sprintf("failed to get node pools: "), err
 	}
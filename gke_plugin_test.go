@@ -0,0 +1,98 @@
+package metricsexporter
+
+import (
+    "context"
+    "encoding/json"
+    "encoding/pem"
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "testing"
+)
+
+/* newTestKubeClient returns a client for the test API server srv.
+ */
+func newTestKubeClient(t *testing.T, srv *httptest.Server) *kubeClient {
+    ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+    kube, err := newKubeClient(context.Background(), srv.URL, ca, nil)
+    if err != nil {
+        t.Fatalf("newKubeClient: %v", err)
+    }
+    return kube
+}
+
+func TestListGkePods(t *testing.T) {
+    var requests int
+    srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        requests++
+        if r.URL.Path != "/api/v1/namespaces/default/pods" {
+            t.Errorf("path = %s, want /api/v1/namespaces/default/pods", r.URL.Path)
+        }
+        if got := r.URL.Query().Get("labelSelector"); got != "app=web" {
+            t.Errorf("labelSelector = %q, want app=web", got)
+        }
+        if got := r.URL.Query().Get("limit"); got != KUBE_LIST_LIMIT {
+            t.Errorf("limit = %q, want %s", got, KUBE_LIST_LIMIT)
+        }
+        // Two pages: the second one is requested with the continue token of the first.
+        switch r.URL.Query().Get("continue") {
+        case "":
+            fmt.Fprint(w, `{"metadata": {"continue": "page2"}, "items": [
+                {"metadata": {"name": "web-1", "namespace": "default", "ownerReferences": [{"kind": "ReplicaSet", "name": "web-abc", "controller": true}]},
+                 "spec": {"nodeName": "node-1"},
+                 "status": {"phase": "Running", "containerStatuses": [{"name": "web", "ready": true, "restartCount": 2}, {"name": "proxy", "ready": true, "restartCount": 1}]}}]}`)
+        case "page2":
+            fmt.Fprint(w, `{"metadata": {}, "items": [
+                {"metadata": {"name": "web-2", "namespace": "default"}, "status": {"phase": "Pending"}}]}`)
+        default:
+            t.Errorf("unexpected continue token %q", r.URL.Query().Get("continue"))
+        }
+    }))
+    defer srv.Close()
+
+    out, err := listGkePods(newTestKubeClient(t, srv), "default", "app=web")
+    if err != nil {
+        t.Fatalf("listGkePods: %s%v", out, err)
+    }
+    if requests != 2 {
+        t.Errorf("got %d requests, want 2", requests)
+    }
+
+    var pods []gkePod
+    if err := json.Unmarshal([]byte(out), &pods); err != nil {
+        t.Fatalf("invalid json %s: %v", out, err)
+    }
+    want := []gkePod{
+        {Name: "web-1", Namespace: "default", Phase: "Running", Node: "node-1", Restarts: 3, Owner: "ReplicaSet/web-abc"},
+        {Name: "web-2", Namespace: "default", Phase: "Pending"},
+    }
+    if len(pods) != len(want) {
+        t.Fatalf("got %d pods, want %d", len(pods), len(want))
+    }
+    for i := range want {
+        if pods[i] != want[i] {
+            t.Errorf("pod %d = %+v, want %+v", i, pods[i], want[i])
+        }
+    }
+}
+
+func TestListGkePodsAllNamespaces(t *testing.T) {
+    srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.URL.Path != "/api/v1/pods" {
+            t.Errorf("path = %s, want /api/v1/pods", r.URL.Path)
+        }
+        if _, ok := r.URL.Query()["labelSelector"]; ok {
+            t.Errorf("unexpected labelSelector %q", r.URL.Query().Get("labelSelector"))
+        }
+        fmt.Fprint(w, `{"items": []}`)
+    }))
+    defer srv.Close()
+
+    out, err := listGkePods(newTestKubeClient(t, srv), "", "")
+    if err != nil {
+        t.Fatalf("listGkePods: %s%v", out, err)
+    }
+    if out != "[]" {
+        t.Errorf("listGkePods = %s, want []", out)
+    }
+}
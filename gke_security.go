@@ -0,0 +1,141 @@
+package metricsexporter
+/**
+ * Security posture audit of GKE clusters against the hardening guide.
+ *
+ * @see https://cloud.google.com/kubernetes-engine/docs/how-to/hardening-your-cluster
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+
+    gke  "google.golang.org/api/container/v1"
+)
+
+/* Result of a single security check.
+ */
+type gkeSecurityFinding struct {
+    Check   string  `json:"check"`
+    Passed  bool    `json:"passed"`
+    Detail  string  `json:"detail,omitempty"`
+}
+
+/* Security audit of a cluster.
+ */
+type gkeSecurityAudit struct {
+    Cluster   string                `json:"cluster"`
+    Location  string                `json:"location"`
+    Score     float64               `json:"complianceScore"`
+    Findings  []gkeSecurityFinding  `json:"findings"`
+}
+
+/* getSecurityAudit audits every cluster in the location.
+ */
+func (g *GKE) getSecurityAudit() (string, error) {
+    clusters, err := g.listClusters()
+    if err != nil {
+        return fmt.Sprintf("failed to list clusters: "), err
+    }
+
+    var res []gkeSecurityAudit
+    for _, c := range clusters {
+        res = append(res, newGkeSecurityAudit(c))
+    }
+
+    if err := g.emitSecurityAudit(res); err != nil {
+        return fmt.Sprintf("failed to emit security audit metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newGkeSecurityAudit runs every security check on cluster c.
+ * The compliance score is the ratio of checks that passed.
+ */
+func newGkeSecurityAudit(c *gke.Cluster) gkeSecurityAudit {
+    res := gkeSecurityAudit{
+        Cluster:  c.Name,
+        Location: c.Location,
+    }
+    // Every check has a detail for when it passed and one for when it failed.
+    add := func(check string, passed bool, ok, failed string) {
+        detail := ok
+        if !passed {
+            detail = failed
+        }
+        res.Findings = append(res.Findings, gkeSecurityFinding{Check: check, Passed: passed, Detail: detail})
+    }
+
+    private := c.PrivateClusterConfig != nil && c.PrivateClusterConfig.EnablePrivateEndpoint
+    add("private_endpoint", private, "master is only reachable from its private endpoint",
+        "master is reachable from its public endpoint")
+
+    authorized := c.MasterAuthorizedNetworksConfig != nil && c.MasterAuthorizedNetworksConfig.Enabled
+    add("master_authorized_networks", authorized, "master access is restricted to authorized networks",
+        "master access is not restricted to authorized networks")
+
+    abac := c.LegacyAbac != nil && c.LegacyAbac.Enabled
+    add("legacy_abac_disabled", !abac, "legacy ABAC authorization is disabled",
+        "legacy ABAC authorization is enabled")
+
+    basicAuth := c.MasterAuth != nil && (c.MasterAuth.Username != "" || c.MasterAuth.Password != "")
+    add("basic_auth_disabled", !basicAuth, "basic authentication with a static password is disabled",
+        "basic authentication with a static password is enabled")
+
+    clientCert := c.MasterAuth != nil && (c.MasterAuth.ClientCertificate != "" ||
+        (c.MasterAuth.ClientCertificateConfig != nil && c.MasterAuth.ClientCertificateConfig.IssueClientCertificate))
+    add("client_certificate_disabled", !clientCert, "client certificate authentication is disabled",
+        "client certificate authentication is enabled")
+
+    workloadIdentity := c.WorkloadIdentityConfig != nil && c.WorkloadIdentityConfig.WorkloadPool != ""
+    add("workload_identity", workloadIdentity, "workload identity is enabled",
+        "workload identity is disabled")
+
+    shielded := c.ShieldedNodes != nil && c.ShieldedNodes.Enabled
+    add("shielded_nodes", shielded, "shielded GKE nodes are enabled",
+        "shielded GKE nodes are disabled")
+
+    netpol := c.NetworkPolicy != nil && c.NetworkPolicy.Enabled
+    add("network_policy", netpol, "network policy enforcement is enabled",
+        "network policy enforcement is disabled")
+
+    channel := ""
+    if c.ReleaseChannel != nil && c.ReleaseChannel.Channel != "UNSPECIFIED" {
+        channel = c.ReleaseChannel.Channel
+    }
+    add("release_channel", channel != "", fmt.Sprintf("cluster is enrolled in the %s release channel", channel),
+        "cluster is not enrolled in a release channel")
+
+    passed := 0
+    for _, f := range res.Findings {
+        if f.Passed {
+            passed++
+        }
+    }
+    res.Score = float64(passed) / float64(len(res.Findings))
+    return res
+}
+
+/* emitSecurityAudit sends the compliance score and the result of every check as metrics.
+ */
+func (g *GKE) emitSecurityAudit(audits []gkeSecurityAudit) error {
+    if !g.EnableEmitter {
+        return nil
+    }
+
+    score := newGaugeVec("gcp_gke_security_compliance_score", "Ratio of the security checks passed by the cluster.", "project", "location", "cluster")
+    check := newGaugeVec("gcp_gke_security_check_passed", "Whether the cluster passed a security check.", "project", "location", "cluster", "check")
+
+    for _, a := range audits {
+        score.WithLabelValues(g.Project, a.Location, a.Cluster).Set(a.Score)
+        for _, f := range a.Findings {
+            check.WithLabelValues(g.Project, a.Location, a.Cluster, f.Check).Set(boolToFloat(f.Passed))
+        }
+    }
+
+    return emitCollectors(g.emitter, g.emitTarget("gke.security.audit"), g.Project, score, check)
+}
@@ -0,0 +1,73 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    gke  "google.golang.org/api/container/v1"
+)
+
+func TestNewGkeSecurityAudit(t *testing.T) {
+    tests := []struct {
+        name     string
+        cluster  *gke.Cluster
+        check    string
+        passed   bool
+        detail   string
+    }{
+        {
+            "release channel",
+            &gke.Cluster{ReleaseChannel: &gke.ReleaseChannel{Channel: "REGULAR"}},
+            "release_channel", true, "cluster is enrolled in the REGULAR release channel",
+        },
+        {
+            "no release channel",
+            &gke.Cluster{},
+            "release_channel", false, "cluster is not enrolled in a release channel",
+        },
+        {
+            "unspecified release channel",
+            &gke.Cluster{ReleaseChannel: &gke.ReleaseChannel{Channel: "UNSPECIFIED"}},
+            "release_channel", false, "cluster is not enrolled in a release channel",
+        },
+        {
+            "public endpoint",
+            &gke.Cluster{},
+            "private_endpoint", false, "master is reachable from its public endpoint",
+        },
+        {
+            "legacy abac",
+            &gke.Cluster{LegacyAbac: &gke.LegacyAbac{Enabled: true}},
+            "legacy_abac_disabled", false, "legacy ABAC authorization is enabled",
+        },
+        {
+            "basic auth disabled",
+            &gke.Cluster{MasterAuth: &gke.MasterAuth{}},
+            "basic_auth_disabled", true, "basic authentication with a static password is disabled",
+        },
+    }
+    for _, tt := range tests {
+        audit := newGkeSecurityAudit(tt.cluster)
+        found := false
+        for _, f := range audit.Findings {
+            if f.Check != tt.check {
+                continue
+            }
+            found = true
+            if f.Passed != tt.passed || f.Detail != tt.detail {
+                t.Errorf("%s: %s = %v %q, want %v %q", tt.name, f.Check, f.Passed, f.Detail, tt.passed, tt.detail)
+            }
+        }
+        if !found {
+            t.Errorf("%s: check %s not run", tt.name, tt.check)
+        }
+    }
+}
+
+func TestGkeSecurityAuditScore(t *testing.T) {
+    audit := newGkeSecurityAudit(&gke.Cluster{})
+    // Only the checks of disabled legacy features pass on an empty cluster.
+    want := 3.0 / float64(len(audit.Findings))
+    if audit.Score != want {
+        t.Errorf("Score = %v, want %v", audit.Score, want)
+    }
+}
@@ -0,0 +1,249 @@
+package metricsexporter
+/**
+ * GKE upgrade and version skew reporting.
+ *
+ * Compares the master and node pool versions of every cluster with the
+ * versions GKE currently offers in the cluster location.
+ *
+ * @see https://cloud.google.com/kubernetes-engine/versioning
+ * @see https://cloud.google.com/kubernetes-engine/docs/reference/rest/v1/projects.locations/getServerConfig
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+
+    gke  "google.golang.org/api/container/v1"
+)
+
+const (
+    GKE_DEFAULT_MAX_MINOR_SKEW = 2
+)
+
+/* Version skew of a node pool against its master.
+ */
+type gkeNodePoolVersion struct {
+    Name               string    `json:"name"`
+    Version            string    `json:"version"`
+    MinorsBehind       int       `json:"minorVersionsBehindMaster"`
+    Skewed             bool      `json:"skewed"`
+    AvailableUpgrades  []string  `json:"availableUpgrades"`
+}
+
+/* Maintenance exclusion of a cluster.
+ */
+type gkeMaintenanceExclusion struct {
+    Name       string  `json:"name"`
+    StartTime  string  `json:"startTime"`
+    EndTime    string  `json:"endTime"`
+    Pending    bool    `json:"pending"`
+}
+
+/* Version report of a cluster.
+ */
+type gkeVersionReport struct {
+    Cluster                  string                     `json:"cluster"`
+    Location                 string                     `json:"location"`
+    ReleaseChannel           string                     `json:"releaseChannel,omitempty"`
+    MasterVersion            string                     `json:"masterVersion"`
+    AvailableMasterUpgrades  []string                   `json:"availableMasterUpgrades"`
+    NearEndOfSupport         bool                       `json:"nearEndOfSupport"`
+    Unsupported              bool                       `json:"unsupported"`
+    NodePools                []gkeNodePoolVersion       `json:"nodePools"`
+    MaintenanceWindow        string                     `json:"maintenanceWindow,omitempty"`
+    MaintenanceExclusions    []gkeMaintenanceExclusion  `json:"maintenanceExclusions,omitempty"`
+}
+
+/* getVersionsReport reports the available upgrades and version skew of every
+ * cluster in the location. Arg1 optionally sets the number of minor versions
+ * a node pool may lag behind its master before it is reported as skewed.
+ */
+func (g *GKE) getVersionsReport() (string, error) {
+    maxSkew := GKE_DEFAULT_MAX_MINOR_SKEW
+    if g.Arg1 != "" {
+        n, err := strconv.Atoi(g.Arg1)
+        if err != nil {
+            return fmt.Sprintf("invalid max minor version skew: "), err
+        }
+        maxSkew = n
+    }
+
+    clusters, err := g.listClusters()
+    if err != nil {
+        return fmt.Sprintf("failed to list clusters: "), err
+    }
+
+    // Server configs are per location, so fetch each one once.
+    configs := map[string]*gke.ServerConfig{}
+    var res []gkeVersionReport
+    for _, c := range clusters {
+        cfg, ok := configs[c.Location]
+        if !ok {
+            name := fmt.Sprintf("projects/%s/locations/%s", g.Project, c.Location)
+            cfg, err = g.client.Projects.Locations.GetServerConfig(name).Do()
+            if err != nil {
+                return fmt.Sprintf("failed to get server config: "), err
+            }
+            configs[c.Location] = cfg
+        }
+        res = append(res, newGkeVersionReport(c, cfg, maxSkew, time.Now()))
+    }
+
+    if err := g.emitVersions(res); err != nil {
+        return fmt.Sprintf("failed to emit version metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newGkeVersionReport compares the versions of cluster c with cfg.
+ */
+func newGkeVersionReport(c *gke.Cluster, cfg *gke.ServerConfig, maxSkew int, now time.Time) gkeVersionReport {
+    res := gkeVersionReport{
+        Cluster:                 c.Name,
+        Location:                c.Location,
+        MasterVersion:           c.CurrentMasterVersion,
+        AvailableMasterUpgrades: []string{},
+    }
+
+    // Clusters enrolled in a release channel can only use the versions of the channel.
+    validMaster := cfg.ValidMasterVersions
+    if c.ReleaseChannel != nil && c.ReleaseChannel.Channel != "" && c.ReleaseChannel.Channel != "UNSPECIFIED" {
+        res.ReleaseChannel = c.ReleaseChannel.Channel
+        for _, ch := range cfg.Channels {
+            if ch.Channel == res.ReleaseChannel {
+                validMaster = ch.ValidVersions
+            }
+        }
+    }
+
+    master := parseGkeVersion(c.CurrentMasterVersion)
+    oldestMinor := -1
+    for _, v := range validMaster {
+        pv := parseGkeVersion(v)
+        if compareGkeVersions(pv, master) > 0 {
+            res.AvailableMasterUpgrades = append(res.AvailableMasterUpgrades, v)
+        }
+        if pv[0] == master[0] && (oldestMinor < 0 || pv[1] < oldestMinor) {
+            oldestMinor = pv[1]
+        }
+    }
+    // The oldest minor version offered is the next one to reach end of support.
+    res.Unsupported = oldestMinor < 0 || master[1] < oldestMinor
+    res.NearEndOfSupport = !res.Unsupported && master[1] == oldestMinor
+
+    for _, np := range c.NodePools {
+        nv := parseGkeVersion(np.Version)
+        pool := gkeNodePoolVersion{
+            Name:              np.Name,
+            Version:           np.Version,
+            MinorsBehind:      master[1] - nv[1],
+            AvailableUpgrades: []string{},
+        }
+        pool.Skewed = pool.MinorsBehind > maxSkew
+        for _, v := range cfg.ValidNodeVersions {
+            pv := parseGkeVersion(v)
+            // Nodes can never run a newer version than the master.
+            if compareGkeVersions(pv, nv) > 0 && compareGkeVersions(pv, master) <= 0 {
+                pool.AvailableUpgrades = append(pool.AvailableUpgrades, v)
+            }
+        }
+        res.NodePools = append(res.NodePools, pool)
+    }
+
+    if c.MaintenancePolicy != nil && c.MaintenancePolicy.Window != nil {
+        w := c.MaintenancePolicy.Window
+        if w.DailyMaintenanceWindow != nil {
+            res.MaintenanceWindow = fmt.Sprintf("daily at %s for %s", w.DailyMaintenanceWindow.StartTime, w.DailyMaintenanceWindow.Duration)
+        } else if w.RecurringWindow != nil && w.RecurringWindow.Window != nil {
+            res.MaintenanceWindow = fmt.Sprintf("%s from %s to %s", w.RecurringWindow.Recurrence,
+                w.RecurringWindow.Window.StartTime, w.RecurringWindow.Window.EndTime)
+        }
+        for name, tw := range w.MaintenanceExclusions {
+            ex := gkeMaintenanceExclusion{
+                Name:      name,
+                StartTime: tw.StartTime,
+                EndTime:   tw.EndTime,
+            }
+            if end, err := time.Parse(time.RFC3339, tw.EndTime); err == nil {
+                ex.Pending = end.After(now)
+            }
+            res.MaintenanceExclusions = append(res.MaintenanceExclusions, ex)
+        }
+    }
+
+    return res
+}
+
+/* emitVersions sends the upgrade and skew status of every cluster as metrics.
+ */
+func (g *GKE) emitVersions(reports []gkeVersionReport) error {
+    if !g.EnableEmitter {
+        return nil
+    }
+
+    labels := []string{"project", "location", "cluster"}
+    upgrades := newGaugeVec("gcp_gke_master_upgrades_available", "Number of newer master versions the cluster can be upgraded to.", labels...)
+    eos := newGaugeVec("gcp_gke_master_near_end_of_support", "Whether the master runs the oldest (1) or an unsupported (2) minor version.", labels...)
+    exclusions := newGaugeVec("gcp_gke_maintenance_exclusions_pending", "Number of maintenance exclusions that have not ended yet.", labels...)
+    skew := newGaugeVec("gcp_gke_nodepool_minor_versions_behind", "Number of minor versions the node pool lags behind its master.", "project", "location", "cluster", "nodepool")
+
+    for _, r := range reports {
+        upgrades.WithLabelValues(g.Project, r.Location, r.Cluster).Set(float64(len(r.AvailableMasterUpgrades)))
+        v := 0.0
+        if r.Unsupported {
+            v = 2
+        } else if r.NearEndOfSupport {
+            v = 1
+        }
+        eos.WithLabelValues(g.Project, r.Location, r.Cluster).Set(v)
+        pending := 0
+        for _, ex := range r.MaintenanceExclusions {
+            if ex.Pending {
+                pending++
+            }
+        }
+        exclusions.WithLabelValues(g.Project, r.Location, r.Cluster).Set(float64(pending))
+        for _, np := range r.NodePools {
+            skew.WithLabelValues(g.Project, r.Location, r.Cluster, np.Name).Set(float64(np.MinorsBehind))
+        }
+    }
+
+    return emitCollectors(g.emitter, g.emitTarget("gke.versions.report"), g.Project, upgrades, eos, exclusions, skew)
+}
+
+/* parseGkeVersion splits a GKE version such as "1.16.13-gke.401" into
+ * its major, minor, patch and gke build numbers. Missing or invalid
+ * parts are 0.
+ */
+func parseGkeVersion(version string) [4]int {
+    var res [4]int
+    parts := strings.SplitN(version, "-gke.", 2)
+    for i, p := range strings.SplitN(parts[0], ".", 3) {
+        res[i], _ = strconv.Atoi(p)
+    }
+    if len(parts) == 2 {
+        res[3], _ = strconv.Atoi(parts[1])
+    }
+    return res
+}
+
+/* compareGkeVersions returns -1, 0 or 1 if a is older, the same or newer than b.
+ */
+func compareGkeVersions(a, b [4]int) int {
+    for i := range a {
+        if a[i] < b[i] {
+            return -1
+        } else if a[i] > b[i] {
+            return 1
+        }
+    }
+    return 0
+}
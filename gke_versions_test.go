@@ -0,0 +1,121 @@
+package metricsexporter
+
+import (
+    "testing"
+    "time"
+
+    gke  "google.golang.org/api/container/v1"
+)
+
+func TestParseGkeVersion(t *testing.T) {
+    tests := []struct {
+        version  string
+        want     [4]int
+    }{
+        {"1.16.13-gke.401", [4]int{1, 16, 13, 401}},
+        {"1.21.14-gke.100", [4]int{1, 21, 14, 100}},
+        {"1.21.5", [4]int{1, 21, 5, 0}},
+        {"1.21", [4]int{1, 21, 0, 0}},
+        {"latest", [4]int{}},
+        {"", [4]int{}},
+    }
+    for _, tt := range tests {
+        if got := parseGkeVersion(tt.version); got != tt.want {
+            t.Errorf("parseGkeVersion(%s) = %v, want %v", tt.version, got, tt.want)
+        }
+    }
+}
+
+func TestCompareGkeVersions(t *testing.T) {
+    tests := []struct {
+        a, b  string
+        want  int
+    }{
+        // Numeric, not lexical, ordering.
+        {"1.21.5-gke.1302", "1.21.14-gke.100", -1},
+        {"1.21.14-gke.100", "1.21.5-gke.1302", 1},
+        {"1.9.7-gke.1", "1.10.0-gke.1", -1},
+        {"1.21.5-gke.1302", "1.21.5-gke.901", 1},
+        {"1.21.5-gke.1302", "1.21.5-gke.1302", 0},
+        {"2.0.0-gke.1", "1.30.9-gke.9", 1},
+    }
+    for _, tt := range tests {
+        if got := compareGkeVersions(parseGkeVersion(tt.a), parseGkeVersion(tt.b)); got != tt.want {
+            t.Errorf("compareGkeVersions(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+        }
+    }
+}
+
+func TestNewGkeVersionReport(t *testing.T) {
+    now := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+    cfg := &gke.ServerConfig{
+        ValidMasterVersions: []string{"1.20.9-gke.700", "1.19.12-gke.2100", "1.18.20-gke.900"},
+        ValidNodeVersions:   []string{"1.20.9-gke.700", "1.19.12-gke.2100", "1.18.20-gke.900", "1.17.17-gke.9100"},
+        Channels: []*gke.ReleaseChannelConfig{
+            {Channel: "RAPID", ValidVersions: []string{"1.21.1-gke.2200", "1.20.9-gke.700"}},
+        },
+    }
+    tests := []struct {
+        name         string
+        master       string
+        channel      string
+        upgrades     int
+        nearEnd      bool
+        unsupported  bool
+    }{
+        {"newest", "1.20.9-gke.700", "", 0, false, false},
+        {"middle", "1.19.12-gke.1000", "", 2, false, false},
+        {"oldest minor offered", "1.18.20-gke.900", "", 2, true, false},
+        {"older than every version offered", "1.17.17-gke.9100", "", 3, false, true},
+        {"release channel versions", "1.20.9-gke.700", "RAPID", 1, true, false},
+    }
+    for _, tt := range tests {
+        c := &gke.Cluster{
+            Name:                 "c",
+            Location:             "us-central1",
+            CurrentMasterVersion: tt.master,
+            NodePools:            []*gke.NodePool{{Name: "pool", Version: "1.17.17-gke.9100"}},
+        }
+        if tt.channel != "" {
+            c.ReleaseChannel = &gke.ReleaseChannel{Channel: tt.channel}
+        }
+        r := newGkeVersionReport(c, cfg, GKE_DEFAULT_MAX_MINOR_SKEW, now)
+        if len(r.AvailableMasterUpgrades) != tt.upgrades || r.NearEndOfSupport != tt.nearEnd || r.Unsupported != tt.unsupported {
+            t.Errorf("%s: upgrades %v, near end of support %v, unsupported %v", tt.name, r.AvailableMasterUpgrades, r.NearEndOfSupport, r.Unsupported)
+        }
+    }
+
+    c := &gke.Cluster{
+        Name:                 "c",
+        CurrentMasterVersion: "1.20.9-gke.700",
+        NodePools: []*gke.NodePool{
+            {Name: "current", Version: "1.20.9-gke.700"},
+            {Name: "old", Version: "1.17.17-gke.9100"},
+        },
+        MaintenancePolicy: &gke.MaintenancePolicy{Window: &gke.MaintenanceWindow{
+            DailyMaintenanceWindow: &gke.DailyMaintenanceWindow{StartTime: "03:00", Duration: "PT4H0M0S"},
+            MaintenanceExclusions: map[string]gke.TimeWindow{
+                "past":   {StartTime: "2021-01-01T00:00:00Z", EndTime: "2021-01-02T00:00:00Z"},
+                "future": {StartTime: "2021-06-01T00:00:00Z", EndTime: "2021-07-01T00:00:00Z"},
+            },
+        }},
+    }
+    r := newGkeVersionReport(c, cfg, GKE_DEFAULT_MAX_MINOR_SKEW, now)
+    if p := r.NodePools[0]; p.MinorsBehind != 0 || p.Skewed || len(p.AvailableUpgrades) != 0 {
+        t.Errorf("current node pool = %+v", p)
+    }
+    // Node pools can be upgraded up to the master version.
+    if p := r.NodePools[1]; p.MinorsBehind != 3 || !p.Skewed || len(p.AvailableUpgrades) != 3 {
+        t.Errorf("old node pool = %+v", p)
+    }
+    if r.MaintenanceWindow != "daily at 03:00 for PT4H0M0S" {
+        t.Errorf("maintenance window = %q", r.MaintenanceWindow)
+    }
+    pending := map[string]bool{}
+    for _, ex := range r.MaintenanceExclusions {
+        pending[ex.Name] = ex.Pending
+    }
+    if len(pending) != 2 || pending["past"] || !pending["future"] {
+        t.Errorf("maintenance exclusions = %+v", r.MaintenanceExclusions)
+    }
+}
@@ -0,0 +1,192 @@
+package metricsexporter
+/**
+ * Fleet-wide summary of the Kubernetes workloads running on every
+ * GKE cluster of a location, read directly from each cluster API server.
+ *
+ * It gives a view similar to kube-state-metrics without having to
+ * deploy it in every cluster.
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+
+    gke  "google.golang.org/api/container/v1"
+)
+
+/* Desired vs ready replicas of a deployment, statefulset or daemonset.
+ */
+type gkeWorkload struct {
+    Kind       string  `json:"kind"`
+    Namespace  string  `json:"namespace"`
+    Name       string  `json:"name"`
+    Desired    int32   `json:"desired"`
+    Ready      int32   `json:"ready"`
+}
+
+/* Status conditions of a node.
+ */
+type gkeNodeConditions struct {
+    Name        string             `json:"name"`
+    Conditions  map[string]string  `json:"conditions"`
+}
+
+/* Workload summary of a cluster.
+ */
+type gkeWorkloadSummary struct {
+    Cluster    string                       `json:"cluster"`
+    Location   string                       `json:"location"`
+    Workloads  []gkeWorkload                `json:"workloads"`
+    Pods       map[string]map[string]int    `json:"podsByNamespaceAndPhase"`
+    Nodes      []gkeNodeConditions          `json:"nodes"`
+    Error      string                       `json:"error,omitempty"`
+}
+
+/* getWorkloadsSummary summarizes the workloads of every cluster in the location.
+ * Clusters whose API server cannot be reached are reported with an error
+ * instead of failing the whole request.
+ */
+func (g *GKE) getWorkloadsSummary() (string, error) {
+    clusters, err := g.listClusters()
+    if err != nil {
+        return fmt.Sprintf("failed to list clusters: "), err
+    }
+
+    var res []gkeWorkloadSummary
+    for _, c := range clusters {
+        sum := gkeWorkloadSummary{
+            Cluster:  c.Name,
+            Location: c.Location,
+        }
+        if err := g.summarizeWorkloads(c, &sum); err != nil {
+            sum.Error = err.Error()
+        }
+        res = append(res, sum)
+    }
+
+    if err := g.emitWorkloads(res); err != nil {
+        return fmt.Sprintf("failed to emit workload metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* summarizeWorkloads fills sum with the workloads, pods and nodes of cluster c.
+ */
+func (g *GKE) summarizeWorkloads(c *gke.Cluster, sum *gkeWorkloadSummary) error {
+    kube, err := g.newClusterKubeClient(c)
+    if err != nil {
+        return err
+    }
+    return summarizeKubeWorkloads(kube, g.KubeNamespace, g.LabelSelector, sum)
+}
+
+/* summarizeKubeWorkloads fills sum from the API server of kube.
+ */
+func summarizeKubeWorkloads(kube *kubeClient, namespace, selector string, sum *gkeWorkloadSummary) error {
+    for _, kind := range []string{"deployments", "statefulsets"} {
+        items, err := kube.listReplicated(namespace, kind, selector)
+        if err != nil {
+            return err
+        }
+        for _, it := range items {
+            w := gkeWorkload{
+                Kind:      kind,
+                Namespace: it.Metadata.Namespace,
+                Name:      it.Metadata.Name,
+                Desired:   it.Status.Replicas,
+                Ready:     it.Status.ReadyReplicas,
+            }
+            if it.Spec.Replicas != nil {
+                w.Desired = *it.Spec.Replicas
+            }
+            sum.Workloads = append(sum.Workloads, w)
+        }
+    }
+
+    daemons, err := kube.listDaemonSets(namespace, selector)
+    if err != nil {
+        return err
+    }
+    for _, it := range daemons {
+        sum.Workloads = append(sum.Workloads, gkeWorkload{
+            Kind:      "daemonsets",
+            Namespace: it.Metadata.Namespace,
+            Name:      it.Metadata.Name,
+            Desired:   it.Status.DesiredNumberScheduled,
+            Ready:     it.Status.NumberReady,
+        })
+    }
+
+    pods, err := kube.listPods(namespace, selector)
+    if err != nil {
+        return err
+    }
+    sum.Pods = map[string]map[string]int{}
+    for _, p := range pods {
+        if sum.Pods[p.Metadata.Namespace] == nil {
+            sum.Pods[p.Metadata.Namespace] = map[string]int{}
+        }
+        sum.Pods[p.Metadata.Namespace][p.Status.Phase]++
+    }
+
+    nodes, err := kube.listNodes()
+    if err != nil {
+        return err
+    }
+    for _, n := range nodes {
+        nc := gkeNodeConditions{
+            Name:       n.Metadata.Name,
+            Conditions: map[string]string{},
+        }
+        for _, cond := range n.Status.Conditions {
+            nc.Conditions[cond.Type] = cond.Status
+        }
+        sum.Nodes = append(sum.Nodes, nc)
+    }
+
+    return nil
+}
+
+/* emitWorkloads sends the workload summary of every cluster as metrics.
+ */
+func (g *GKE) emitWorkloads(sums []gkeWorkloadSummary) error {
+    if !g.EnableEmitter {
+        return nil
+    }
+
+    wlabels := []string{"project", "location", "cluster", "namespace", "kind", "name"}
+    desired := newGaugeVec("gcp_gke_workload_replicas_desired", "Desired replicas of a deployment, statefulset or daemonset.", wlabels...)
+    ready := newGaugeVec("gcp_gke_workload_replicas_ready", "Ready replicas of a deployment, statefulset or daemonset.", wlabels...)
+    pods := newGaugeVec("gcp_gke_pods", "Number of pods per namespace and phase.", "project", "location", "cluster", "namespace", "phase")
+    conds := newGaugeVec("gcp_gke_node_condition", "Status of a node condition (1 if the condition has this status).", "project", "location", "cluster", "node", "condition", "status")
+    up := newGaugeVec("gcp_gke_cluster_api_up", "Whether the cluster API server could be queried.", "project", "location", "cluster")
+
+    for _, s := range sums {
+        if s.Error != "" {
+            up.WithLabelValues(g.Project, s.Location, s.Cluster).Set(0)
+            continue
+        }
+        up.WithLabelValues(g.Project, s.Location, s.Cluster).Set(1)
+        for _, w := range s.Workloads {
+            desired.WithLabelValues(g.Project, s.Location, s.Cluster, w.Namespace, w.Kind, w.Name).Set(float64(w.Desired))
+            ready.WithLabelValues(g.Project, s.Location, s.Cluster, w.Namespace, w.Kind, w.Name).Set(float64(w.Ready))
+        }
+        for ns, phases := range s.Pods {
+            for phase, count := range phases {
+                pods.WithLabelValues(g.Project, s.Location, s.Cluster, ns, phase).Set(float64(count))
+            }
+        }
+        for _, n := range s.Nodes {
+            for cond, status := range n.Conditions {
+                conds.WithLabelValues(g.Project, s.Location, s.Cluster, n.Name, cond, status).Set(1)
+            }
+        }
+    }
+
+    return emitCollectors(g.emitter, g.emitTarget("gke.workloads.summary"), g.Project, desired, ready, pods, conds, up)
+}
@@ -0,0 +1,75 @@
+package metricsexporter
+
+import (
+    "fmt"
+    "net/http"
+    "net/http/httptest"
+    "reflect"
+    "testing"
+)
+
+func TestSummarizeKubeWorkloads(t *testing.T) {
+    responses := map[string]string{
+        "/apis/apps/v1/namespaces/default/deployments": `{"items": [
+            {"metadata": {"name": "web", "namespace": "default"}, "spec": {"replicas": 3}, "status": {"replicas": 2, "readyReplicas": 2}},
+            {"metadata": {"name": "scaled-down", "namespace": "default"}, "status": {"replicas": 1, "readyReplicas": 0}}]}`,
+        "/apis/apps/v1/namespaces/default/statefulsets": `{"items": [
+            {"metadata": {"name": "db", "namespace": "default"}, "spec": {"replicas": 1}, "status": {"replicas": 1, "readyReplicas": 1}}]}`,
+        "/apis/apps/v1/namespaces/default/daemonsets": `{"items": [
+            {"metadata": {"name": "agent", "namespace": "default"}, "status": {"desiredNumberScheduled": 3, "numberReady": 2}}]}`,
+        "/api/v1/namespaces/default/pods": `{"items": [
+            {"metadata": {"name": "web-1", "namespace": "default"}, "status": {"phase": "Running"}},
+            {"metadata": {"name": "web-2", "namespace": "default"}, "status": {"phase": "Running"}},
+            {"metadata": {"name": "web-3", "namespace": "default"}, "status": {"phase": "Pending"}}]}`,
+        "/api/v1/nodes": `{"items": [
+            {"metadata": {"name": "node-1"}, "status": {"conditions": [{"type": "Ready", "status": "True"}, {"type": "DiskPressure", "status": "False"}]}}]}`,
+    }
+    srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, ok := responses[r.URL.Path]
+        if !ok {
+            t.Errorf("unexpected path %s", r.URL.Path)
+            http.NotFound(w, r)
+            return
+        }
+        if r.URL.Path != "/api/v1/nodes" && r.URL.Query().Get("labelSelector") != "tier=front" {
+            t.Errorf("%s labelSelector = %q, want tier=front", r.URL.Path, r.URL.Query().Get("labelSelector"))
+        }
+        fmt.Fprint(w, body)
+    }))
+    defer srv.Close()
+
+    sum := gkeWorkloadSummary{}
+    if err := summarizeKubeWorkloads(newTestKubeClient(t, srv), "default", "tier=front", &sum); err != nil {
+        t.Fatalf("summarizeKubeWorkloads: %v", err)
+    }
+
+    wantWorkloads := []gkeWorkload{
+        {Kind: "deployments", Namespace: "default", Name: "web", Desired: 3, Ready: 2},
+        {Kind: "deployments", Namespace: "default", Name: "scaled-down", Desired: 1, Ready: 0},
+        {Kind: "statefulsets", Namespace: "default", Name: "db", Desired: 1, Ready: 1},
+        {Kind: "daemonsets", Namespace: "default", Name: "agent", Desired: 3, Ready: 2},
+    }
+    if !reflect.DeepEqual(sum.Workloads, wantWorkloads) {
+        t.Errorf("workloads = %+v, want %+v", sum.Workloads, wantWorkloads)
+    }
+    wantPods := map[string]map[string]int{"default": {"Running": 2, "Pending": 1}}
+    if !reflect.DeepEqual(sum.Pods, wantPods) {
+        t.Errorf("pods = %v, want %v", sum.Pods, wantPods)
+    }
+    wantNodes := []gkeNodeConditions{{Name: "node-1", Conditions: map[string]string{"Ready": "True", "DiskPressure": "False"}}}
+    if !reflect.DeepEqual(sum.Nodes, wantNodes) {
+        t.Errorf("nodes = %+v, want %+v", sum.Nodes, wantNodes)
+    }
+}
+
+func TestSummarizeKubeWorkloadsError(t *testing.T) {
+    srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Error(w, "forbidden", http.StatusForbidden)
+    }))
+    defer srv.Close()
+
+    sum := gkeWorkloadSummary{}
+    if err := summarizeKubeWorkloads(newTestKubeClient(t, srv), "", "", &sum); err == nil {
+        t.Errorf("summarizeKubeWorkloads succeeded, want the API server error")
+    }
+}
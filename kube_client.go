@@ -0,0 +1,299 @@
+package metricsexporter
+/**
+ * Minimal client for the Kubernetes API server of a cluster.
+ * Only the read-only calls and fields needed by the plugins are supported.
+ *
+ * @usage
+ * kube, err := newKubeClient(ctx, "https://1.2.3.4", caCertPem, tokenSource)
+ * pods, err := kube.listPods("default", "app=web")
+ *
+ * @see https://kubernetes.io/docs/reference/generated/kubernetes-api/v1.16/
+ **/
+
+import (
+    "context"
+    "crypto/tls"
+    "crypto/x509"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "io/ioutil"
+    "net/http"
+    "net/url"
+    "time"
+
+    "golang.org/x/oauth2"
+)
+
+const (
+    KUBE_REQUEST_TIMEOUT = 30 * time.Second
+    KUBE_LIST_LIMIT      = "500"
+)
+
+/* Kubernetes API client.
+ */
+type kubeClient struct {
+    context  context.Context
+    host     string
+    client   *http.Client
+}
+
+/* Subset of the Kubernetes ObjectMeta.
+ */
+type kubeObjectMeta struct {
+    Name             string                `json:"name"`
+    Namespace        string                `json:"namespace"`
+    Labels           map[string]string     `json:"labels"`
+    OwnerReferences  []kubeOwnerReference  `json:"ownerReferences"`
+}
+
+/* Subset of the Kubernetes OwnerReference.
+ */
+type kubeOwnerReference struct {
+    Kind        string  `json:"kind"`
+    Name        string  `json:"name"`
+    Controller  bool    `json:"controller"`
+}
+
+/* Subset of the Kubernetes Pod.
+ */
+type kubePod struct {
+    Metadata  kubeObjectMeta  `json:"metadata"`
+    Spec      struct {
+        NodeName  string  `json:"nodeName"`
+    } `json:"spec"`
+    Status    struct {
+        Phase              string  `json:"phase"`
+        ContainerStatuses  []struct {
+            Name          string  `json:"name"`
+            Ready         bool    `json:"ready"`
+            RestartCount  int32   `json:"restartCount"`
+        } `json:"containerStatuses"`
+    } `json:"status"`
+}
+
+/* Subset of the Kubernetes Deployment and StatefulSet.
+ */
+type kubeReplicated struct {
+    Metadata  kubeObjectMeta  `json:"metadata"`
+    Spec      struct {
+        Replicas  *int32  `json:"replicas"`
+    } `json:"spec"`
+    Status    struct {
+        Replicas       int32  `json:"replicas"`
+        ReadyReplicas  int32  `json:"readyReplicas"`
+    } `json:"status"`
+}
+
+/* Subset of the Kubernetes DaemonSet.
+ */
+type kubeDaemonSet struct {
+    Metadata  kubeObjectMeta  `json:"metadata"`
+    Status    struct {
+        DesiredNumberScheduled  int32  `json:"desiredNumberScheduled"`
+        NumberReady             int32  `json:"numberReady"`
+    } `json:"status"`
+}
+
+/* Subset of the Kubernetes Node.
+ */
+type kubeNode struct {
+    Metadata  kubeObjectMeta  `json:"metadata"`
+    Status    struct {
+        Conditions  []struct {
+            Type    string  `json:"type"`
+            Status  string  `json:"status"`
+        } `json:"conditions"`
+    } `json:"status"`
+}
+
+/* Subset of the Kubernetes Service.
+ */
+type kubeService struct {
+    Metadata  kubeObjectMeta  `json:"metadata"`
+    Spec      struct {
+        Type       string  `json:"type"`
+        ClusterIP  string  `json:"clusterIP"`
+    } `json:"spec"`
+}
+
+/* newKubeClient creates a client for the API server at host that trusts
+ * the PEM encoded caCert and authenticates with tokens from ts.
+ * ts may be nil for unauthenticated access.
+ */
+func newKubeClient(ctx context.Context, host string, caCert []byte, ts oauth2.TokenSource) (*kubeClient, error) {
+    pool := x509.NewCertPool()
+    if !pool.AppendCertsFromPEM(caCert) {
+        return nil, errors.New("failed to parse cluster CA certificate")
+    }
+
+    var transport http.RoundTripper = &http.Transport{
+        TLSClientConfig: &tls.Config{RootCAs: pool},
+    }
+    if ts != nil {
+        transport = &oauth2.Transport{Source: ts, Base: transport}
+    }
+
+    return &kubeClient{
+        context: ctx,
+        host:    host,
+        client:  &http.Client{Transport: transport, Timeout: KUBE_REQUEST_TIMEOUT},
+    }, nil
+}
+
+/* get calls the API server at path and decodes the json response into out.
+ */
+func (k *kubeClient) get(path string, params url.Values, out interface{}) error {
+    u := k.host + path
+    if len(params) > 0 {
+        u = u + "?" + params.Encode()
+    }
+    req, err := http.NewRequest(http.MethodGet, u, nil)
+    if err != nil {
+        return err
+    }
+    if k.context != nil {
+        req = req.WithContext(k.context)
+    }
+
+    resp, err := k.client.Do(req)
+    if err != nil {
+        return err
+    }
+    defer resp.Body.Close()
+
+    if resp.StatusCode != http.StatusOK {
+        body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+        return fmt.Errorf("GET %s: %s: %s", path, resp.Status, body)
+    }
+    return json.NewDecoder(resp.Body).Decode(out)
+}
+
+/* list calls the API server at path for every page of the list and
+ * passes every item of the list to add.
+ * @see https://kubernetes.io/docs/reference/using-api/api-concepts/#retrieving-large-results-sets-in-chunks
+ */
+func (k *kubeClient) list(path string, params url.Values, add func(item json.RawMessage) error) error {
+    if params == nil {
+        params = url.Values{}
+    }
+    params.Set("limit", KUBE_LIST_LIMIT)
+    for {
+        var page struct {
+            Metadata  struct {
+                Continue  string  `json:"continue"`
+            } `json:"metadata"`
+            Items     []json.RawMessage  `json:"items"`
+        }
+        if err := k.get(path, params, &page); err != nil {
+            return err
+        }
+        for _, item := range page.Items {
+            if err := add(item); err != nil {
+                return err
+            }
+        }
+        if page.Metadata.Continue == "" {
+            return nil
+        }
+        params.Set("continue", page.Metadata.Continue)
+    }
+}
+
+/* namespacedPath returns the path of resource in namespace, or across
+ * all namespaces if namespace is empty.
+ */
+func namespacedPath(group, namespace, resource string) string {
+    if namespace == "" {
+        return group + "/" + resource
+    }
+    return group + "/namespaces/" + url.PathEscape(namespace) + "/" + resource
+}
+
+/* listPods lists the pods in namespace (all namespaces if empty)
+ * that match the label selector (all pods if empty).
+ */
+func (k *kubeClient) listPods(namespace, selector string) ([]kubePod, error) {
+    var res []kubePod
+    err := k.list(namespacedPath("/api/v1", namespace, "pods"), selectorParams(selector), func(item json.RawMessage) error {
+        var p kubePod
+        if err := json.Unmarshal(item, &p); err != nil {
+            return err
+        }
+        res = append(res, p)
+        return nil
+    })
+    return res, err
+}
+
+/* listReplicated lists the deployments or statefulsets (resource) in namespace.
+ */
+func (k *kubeClient) listReplicated(namespace, resource, selector string) ([]kubeReplicated, error) {
+    var res []kubeReplicated
+    err := k.list(namespacedPath("/apis/apps/v1", namespace, resource), selectorParams(selector), func(item json.RawMessage) error {
+        var r kubeReplicated
+        if err := json.Unmarshal(item, &r); err != nil {
+            return err
+        }
+        res = append(res, r)
+        return nil
+    })
+    return res, err
+}
+
+/* listDaemonSets lists the daemonsets in namespace.
+ */
+func (k *kubeClient) listDaemonSets(namespace, selector string) ([]kubeDaemonSet, error) {
+    var res []kubeDaemonSet
+    err := k.list(namespacedPath("/apis/apps/v1", namespace, "daemonsets"), selectorParams(selector), func(item json.RawMessage) error {
+        var d kubeDaemonSet
+        if err := json.Unmarshal(item, &d); err != nil {
+            return err
+        }
+        res = append(res, d)
+        return nil
+    })
+    return res, err
+}
+
+/* listNodes lists the nodes of the cluster.
+ */
+func (k *kubeClient) listNodes() ([]kubeNode, error) {
+    var res []kubeNode
+    err := k.list("/api/v1/nodes", nil, func(item json.RawMessage) error {
+        var n kubeNode
+        if err := json.Unmarshal(item, &n); err != nil {
+            return err
+        }
+        res = append(res, n)
+        return nil
+    })
+    return res, err
+}
+
+/* listServices lists the services in namespace (all namespaces if empty).
+ */
+func (k *kubeClient) listServices(namespace string) ([]kubeService, error) {
+    var res []kubeService
+    err := k.list(namespacedPath("/api/v1", namespace, "services"), nil, func(item json.RawMessage) error {
+        var s kubeService
+        if err := json.Unmarshal(item, &s); err != nil {
+            return err
+        }
+        res = append(res, s)
+        return nil
+    })
+    return res, err
+}
+
+/* selectorParams returns the query parameters of the label selector
+ * (all objects if empty).
+ */
+func selectorParams(selector string) url.Values {
+    params := url.Values{}
+    if selector != "" {
+        params.Set("labelSelector", selector)
+    }
+    return params
+}
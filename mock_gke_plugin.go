@@ -19,5 +19,6 @@ func (b *gkeBuild) BuildMock() (GKE, error) {
         Cluster:  b.cluster,
         Project:  b.project,
         Zone:     b.zone,
+        Region:   b.region,
     }, nil
 }
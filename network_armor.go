@@ -0,0 +1,149 @@
+package metricsexporter
+/**
+ * Cloud Armor security policies, their rules and the backend services
+ * they protect.
+ *
+ * @see https://cloud.google.com/armor/docs/security-policy-overview
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* Rule of a security policy.
+ */
+type armorRule struct {
+    Priority     int64     `json:"priority"`
+    Action       string    `json:"action"`
+    Preview      bool      `json:"preview"`
+    Description  string    `json:"description,omitempty"`
+    SrcIpRanges  []string  `json:"srcIpRanges,omitempty"`
+    Expression   string    `json:"expression,omitempty"`
+}
+
+/* Security policy and the backend services it is attached to.
+ */
+type armorPolicy struct {
+    Name             string       `json:"name"`
+    Rules            []armorRule  `json:"rules"`
+    PreviewRules     int          `json:"previewRules"`
+    BackendServices  []string     `json:"backendServices"`
+}
+
+/* Security policies of the project.
+ */
+type armorReport struct {
+    Policies             []armorPolicy  `json:"policies"`
+    UnprotectedServices  []string       `json:"unprotectedBackendServices"`
+}
+
+/* getSecurityPoliciesList maps every security policy to the backend
+ * services it protects, and reports the external backend services without
+ * a policy. Only global external backend services can have a policy.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/securityPolicies/list
+ */
+func (n *Network) getSecurityPoliciesList() (string, error) {
+    var policies []*compute.SecurityPolicy
+    err := n.client.SecurityPolicies.List(n.Project).Pages(n.context, func(list *compute.SecurityPolicyList) error {
+        policies = append(policies, list.Items...)
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list security policies: "), err
+    }
+    services, err := n.listAllBackendServices()
+    if err != nil {
+        return fmt.Sprintf("failed to list backend services: "), err
+    }
+
+    res := newArmorReport(policies, services)
+
+    if err := n.emitArmorReport(res); err != nil {
+        return fmt.Sprintf("failed to emit security policy metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newArmorReport attaches the backend services to their policy.
+ */
+func newArmorReport(policies []*compute.SecurityPolicy, services []*compute.BackendService) armorReport {
+    res := armorReport{
+        Policies:            []armorPolicy{},
+        UnprotectedServices: []string{},
+    }
+
+    protected := map[string][]string{}
+    for _, bs := range services {
+        if bs.SecurityPolicy != "" {
+            policy := lastPathElement(bs.SecurityPolicy)
+            protected[policy] = append(protected[policy], bs.Name)
+        } else if bs.Region == "" && bs.LoadBalancingScheme == "EXTERNAL" {
+            res.UnprotectedServices = append(res.UnprotectedServices, bs.Name)
+        }
+    }
+
+    for _, p := range policies {
+        ap := armorPolicy{
+            Name:            p.Name,
+            Rules:           []armorRule{},
+            BackendServices: protected[p.Name],
+        }
+        if ap.BackendServices == nil {
+            ap.BackendServices = []string{}
+        }
+        for _, r := range p.Rules {
+            ar := armorRule{
+                Priority:    r.Priority,
+                Action:      r.Action,
+                Preview:     r.Preview,
+                Description: r.Description,
+            }
+            if r.Match != nil {
+                if r.Match.Config != nil {
+                    ar.SrcIpRanges = r.Match.Config.SrcIpRanges
+                }
+                if r.Match.Expr != nil {
+                    ar.Expression = r.Match.Expr.Expression
+                }
+            }
+            if r.Preview {
+                ap.PreviewRules++
+            }
+            ap.Rules = append(ap.Rules, ar)
+        }
+        sort.Slice(ap.Rules, func(i, j int) bool { return ap.Rules[i].Priority < ap.Rules[j].Priority })
+        res.Policies = append(res.Policies, ap)
+    }
+    return res
+}
+
+/* emitArmorReport sends the rules and protected backend services of every
+ * policy, and the number of unprotected backend services, as metrics.
+ */
+func (n *Network) emitArmorReport(report armorReport) error {
+    if !n.EnableEmitter {
+        return nil
+    }
+
+    rules := newGaugeVec("gcp_armor_policy_rules", "Number of rules of the security policy.", "project", "policy", "preview")
+    services := newGaugeVec("gcp_armor_policy_backend_services", "Number of backend services protected by the security policy.", "project", "policy")
+    unprotected := newGaugeVec("gcp_armor_unprotected_backend_services", "Number of external backend services without a security policy.", "project")
+
+    for _, p := range report.Policies {
+        rules.WithLabelValues(n.Project, p.Name, "true").Set(float64(p.PreviewRules))
+        rules.WithLabelValues(n.Project, p.Name, "false").Set(float64(len(p.Rules) - p.PreviewRules))
+        services.WithLabelValues(n.Project, p.Name).Set(float64(len(p.BackendServices)))
+    }
+    unprotected.WithLabelValues(n.Project).Set(float64(len(report.UnprotectedServices)))
+
+    return emitCollectors(n.emitter, "network.securitypolicies.list", n.Project, rules, services, unprotected)
+}
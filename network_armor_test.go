@@ -0,0 +1,74 @@
+package metricsexporter
+
+import (
+    "reflect"
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestNewArmorReport(t *testing.T) {
+    prefix := COMPUTE_API_PREFIX + "projects/p/"
+    policies := []*compute.SecurityPolicy{
+        {
+            Name: "edge",
+            Rules: []*compute.SecurityPolicyRule{
+                {Priority: 2147483647, Action: "allow", Match: &compute.SecurityPolicyRuleMatcher{Config: &compute.SecurityPolicyRuleMatcherConfig{SrcIpRanges: []string{"*"}}}},
+                {Priority: 1000, Action: "deny(403)", Preview: true, Match: &compute.SecurityPolicyRuleMatcher{Expr: &compute.Expr{Expression: "origin.region_code == 'XX'"}}},
+                {Priority: 100, Action: "deny(403)", Match: &compute.SecurityPolicyRuleMatcher{Config: &compute.SecurityPolicyRuleMatcherConfig{SrcIpRanges: []string{"1.2.3.0/24"}}}},
+            },
+        },
+        {Name: "unused"},
+    }
+    services := []*compute.BackendService{
+        {Name: "web", LoadBalancingScheme: "EXTERNAL", SecurityPolicy: prefix + "global/securityPolicies/edge"},
+        {Name: "api", LoadBalancingScheme: "EXTERNAL", SecurityPolicy: prefix + "global/securityPolicies/edge"},
+        {Name: "static", LoadBalancingScheme: "EXTERNAL"},
+        {Name: "internal", LoadBalancingScheme: "INTERNAL"},
+        {Name: "regional", LoadBalancingScheme: "EXTERNAL", Region: prefix + "regions/us-central1"},
+    }
+
+    res := newArmorReport(policies, services)
+    if !reflect.DeepEqual(res.UnprotectedServices, []string{"static"}) {
+        t.Errorf("got unprotected services %v, want [static]", res.UnprotectedServices)
+    }
+    if len(res.Policies) != 2 {
+        t.Fatalf("got %d policies, want 2", len(res.Policies))
+    }
+
+    tests := []struct {
+        policy      armorPolicy
+        name        string
+        services    []string
+        priorities  []int64
+        preview     int
+    }{
+        {res.Policies[0], "edge", []string{"web", "api"}, []int64{100, 1000, 2147483647}, 1},
+        {res.Policies[1], "unused", []string{}, []int64{}, 0},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            p := tt.policy
+            if p.Name != tt.name || p.PreviewRules != tt.preview {
+                t.Errorf("got policy %s with %d preview rules, want %s with %d", p.Name, p.PreviewRules, tt.name, tt.preview)
+            }
+            if !reflect.DeepEqual(p.BackendServices, tt.services) {
+                t.Errorf("got backend services %v, want %v", p.BackendServices, tt.services)
+            }
+            priorities := []int64{}
+            for _, r := range p.Rules {
+                priorities = append(priorities, r.Priority)
+            }
+            if !reflect.DeepEqual(priorities, tt.priorities) {
+                t.Errorf("got rule priorities %v, want %v", priorities, tt.priorities)
+            }
+        })
+    }
+
+    if r := res.Policies[0].Rules[1]; r.Expression != "origin.region_code == 'XX'" || !r.Preview {
+        t.Errorf("got rule %+v, want the preview expression rule", r)
+    }
+    if r := res.Policies[0].Rules[0]; !reflect.DeepEqual(r.SrcIpRanges, []string{"1.2.3.0/24"}) {
+        t.Errorf("got source ranges %v, want [1.2.3.0/24]", r.SrcIpRanges)
+    }
+}
@@ -0,0 +1,107 @@
+package metricsexporter
+/**
+ * Global and regional SSL certificates of the load balancers, self-managed
+ * or Google-managed, and their expiry.
+ *
+ * @see https://cloud.google.com/load-balancing/docs/ssl-certificates
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "time"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* SSL certificate and its expiry.
+ */
+type sslCertificate struct {
+    Name              string             `json:"name"`
+    Region            string             `json:"region"`
+    Type              string             `json:"type"`
+    Domains           []string           `json:"domains"`
+    ManagedStatus     string             `json:"managedStatus,omitempty"`
+    DomainStatus      map[string]string  `json:"domainStatus,omitempty"`
+    ExpireTime        string             `json:"expireTime,omitempty"`
+    ExpirySeconds     float64            `json:"expirySeconds,omitempty"`
+    ExpireTimeError   string             `json:"expireTimeError,omitempty"`
+}
+
+/* getSslCertificatesList lists every SSL certificate of the project.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/sslCertificates/aggregatedList
+ */
+func (n *Network) getSslCertificatesList() (string, error) {
+    now := time.Now()
+    res := []sslCertificate{}
+    err := n.client.SslCertificates.AggregatedList(n.Project).Pages(n.context, func(list *compute.SslCertificateAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, c := range scoped.SslCertificates {
+                res = append(res, newSslCertificate(c, now))
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list ssl certificates: "), err
+    }
+
+    if n.EnableEmitter {
+        expiry := newGaugeVec("gcp_ssl_certificate_expiry_seconds", "Number of seconds until the SSL certificate expires.", "project", "region", "certificate", "type")
+        for _, c := range res {
+            // Managed certificates are not issued while provisioning.
+            if c.ExpireTime == "" || c.ExpireTimeError != "" {
+                continue
+            }
+            expiry.WithLabelValues(n.Project, c.Region, c.Name, c.Type).Set(c.ExpirySeconds)
+        }
+        if err := emitCollectors(n.emitter, "network.sslcertificates.list", n.Project, expiry); err != nil {
+            return fmt.Sprintf("failed to emit ssl certificate metrics: "), err
+        }
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newSslCertificate converts certificate c. Google-managed certificates
+ * list their domains in the managed configuration. An expire time that
+ * cannot be parsed is reported in ExpireTimeError.
+ */
+func newSslCertificate(c *compute.SslCertificate, now time.Time) sslCertificate {
+    res := sslCertificate{
+        Name:       c.Name,
+        Region:     lastPathElement(c.Region),
+        Type:       c.Type,
+        Domains:    c.SubjectAlternativeNames,
+        ExpireTime: c.ExpireTime,
+    }
+    if res.Region == "" {
+        res.Region = SCOPE_GLOBAL
+    }
+    if res.Type == "" {
+        res.Type = "SELF_MANAGED"
+    }
+    if c.Managed != nil {
+        res.ManagedStatus = c.Managed.Status
+        res.DomainStatus = c.Managed.DomainStatus
+        if len(res.Domains) == 0 {
+            res.Domains = c.Managed.Domains
+        }
+    }
+    if res.Domains == nil {
+        res.Domains = []string{}
+    }
+    if c.ExpireTime != "" {
+        t, err := time.Parse(time.RFC3339, c.ExpireTime)
+        if err != nil {
+            res.ExpireTimeError = err.Error()
+        } else {
+            res.ExpirySeconds = t.Sub(now).Seconds()
+        }
+    }
+    return res
+}
@@ -0,0 +1,52 @@
+package metricsexporter
+
+import (
+    "testing"
+    "time"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestNewSslCertificate(t *testing.T) {
+    now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+    tests := []struct {
+        name        string
+        cert        *compute.SslCertificate
+        region      string
+        certType    string
+        expiry      float64
+        expiryErr   bool
+    }{
+        {
+            "global self-managed",
+            &compute.SslCertificate{Name: "c", ExpireTime: "2020-01-02T00:00:00Z"},
+            SCOPE_GLOBAL, "SELF_MANAGED", 24 * 3600, false,
+        },
+        {
+            "regional expired",
+            &compute.SslCertificate{Name: "c", Type: "SELF_MANAGED", Region: COMPUTE_API_PREFIX + "projects/p/regions/us-central1",
+                ExpireTime: "2019-12-31T23:00:00-00:00"},
+            "us-central1", "SELF_MANAGED", -3600, false,
+        },
+        {
+            "managed provisioning",
+            &compute.SslCertificate{Name: "c", Type: "MANAGED", Managed: &compute.SslCertificateManagedSslCertificate{
+                Status: "PROVISIONING", Domains: []string{"example.com"}}},
+            SCOPE_GLOBAL, "MANAGED", 0, false,
+        },
+        {
+            "unparsable expire time",
+            &compute.SslCertificate{Name: "c", ExpireTime: "tomorrow"},
+            SCOPE_GLOBAL, "SELF_MANAGED", 0, true,
+        },
+    }
+    for _, tt := range tests {
+        c := newSslCertificate(tt.cert, now)
+        if c.Region != tt.region || c.Type != tt.certType || c.ExpirySeconds != tt.expiry || (c.ExpireTimeError != "") != tt.expiryErr {
+            t.Errorf("%s: newSslCertificate() = %+v", tt.name, c)
+        }
+        if c.Domains == nil {
+            t.Errorf("%s: nil domains", tt.name)
+        }
+    }
+}
@@ -0,0 +1,299 @@
+package metricsexporter
+/**
+ * CIDR overlap and routing conflict analysis across the VPC networks of a
+ * project and the networks they are peered with.
+ *
+ * @see https://cloud.google.com/vpc/docs/vpc-peering#restrictions
+ * @see https://cloud.google.com/vpc/docs/routes#routeselection
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "net"
+    "strings"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* IP range of a subnet (primary or secondary) or route.
+ * Only routes have a priority.
+ */
+type networkRange struct {
+    Project   string  `json:"project"`
+    Network   string  `json:"network"`
+    Kind      string  `json:"kind"`
+    Name      string  `json:"name"`
+    Cidr      string  `json:"cidr"`
+    Priority  int64   `json:"priority,omitempty"`
+}
+
+/* Pair of overlapping ranges.
+ */
+type rangeOverlap struct {
+    A       networkRange  `json:"a"`
+    B       networkRange  `json:"b"`
+    Reason  string        `json:"reason"`
+}
+
+/* Route fully covered by other routes or subnet ranges.
+ */
+type shadowedRoute struct {
+    Route       networkRange    `json:"route"`
+    ShadowedBy  []networkRange  `json:"shadowedBy"`
+}
+
+/* Result of the cidr analysis.
+ */
+type cidrAnalysis struct {
+    Overlaps            []rangeOverlap   `json:"overlaps"`
+    ShadowedRoutes      []shadowedRoute  `json:"shadowedRoutes"`
+    PeeringConflicts    []rangeOverlap   `json:"peeringConflicts"`
+    PeerErrors          []string         `json:"peerErrors,omitempty"`
+    Candidate           string           `json:"candidate,omitempty"`
+    CandidateConflicts  []networkRange   `json:"candidateConflicts,omitempty"`
+    CandidateAvailable  bool             `json:"candidateAvailable"`
+}
+
+/* getCidrsAnalysis loads the subnets, custom routes and peerings of the
+ * project and reports conflicting ranges. Set Arg1 to a candidate cidr
+ * to check it against every known range before creating it.
+ */
+func (n *Network) getCidrsAnalysis(candidate string) (string, error) {
+    if candidate != "" {
+        if _, _, err := net.ParseCIDR(candidate); err != nil {
+            return fmt.Sprintf("invalid candidate cidr: "), err
+        }
+    }
+
+    subnets, err := n.listSubnetRanges(n.Project)
+    if err != nil {
+        return fmt.Sprintf("failed to list subnetworks: "), err
+    }
+    var routes []networkRange
+    err = n.client.Routes.List(n.Project).Pages(n.context, func(list *compute.RouteList) error {
+        for _, r := range list.Items {
+            // Subnet routes duplicate the subnet ranges.
+            if r.NextHopNetwork != "" {
+                continue
+            }
+            routes = append(routes, networkRange{n.Project, lastPathElement(r.Network), "route", r.Name, r.DestRange, r.Priority})
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list routes: "), err
+    }
+    networks, err := n.listAllNetworks()
+    if err != nil {
+        return fmt.Sprintf("failed to list networks: "), err
+    }
+
+    res := analyzeCidrs(subnets, routes)
+
+    // Ranges of the peered networks, which may live in other projects.
+    // Every project is listed once, whether the listing fails or not.
+    peerRanges := map[string][]networkRange{}
+    projectRanges := map[string][]networkRange{}
+    projectErrors := map[string]error{}
+    for _, nw := range networks {
+        for _, p := range nw.Peerings {
+            project, network := networkProjectAndName(p.Network)
+            key := project + "/" + network
+            if _, ok := peerRanges[key]; !ok {
+                ranges, listed := projectRanges[project]
+                if _, failed := projectErrors[project]; !listed && !failed {
+                    ranges, err = n.listSubnetRanges(project)
+                    if err != nil {
+                        projectErrors[project] = err
+                        res.PeerErrors = append(res.PeerErrors, fmt.Sprintf("%s: %s", project, err))
+                    } else {
+                        projectRanges[project] = ranges
+                    }
+                }
+                peerRanges[key] = []networkRange{}
+                for _, r := range ranges {
+                    if r.Network == network {
+                        peerRanges[key] = append(peerRanges[key], r)
+                    }
+                }
+            }
+            for _, local := range subnets {
+                if local.Network != nw.Name {
+                    continue
+                }
+                for _, remote := range peerRanges[key] {
+                    if cidrsOverlap(local.Cidr, remote.Cidr) {
+                        res.PeeringConflicts = append(res.PeeringConflicts, rangeOverlap{local, remote,
+                            fmt.Sprintf("peering %s (%s)", p.Name, p.State)})
+                    }
+                }
+            }
+        }
+    }
+
+    if candidate != "" {
+        res.Candidate = candidate
+        all := append(append([]networkRange{}, subnets...), routes...)
+        for _, ranges := range peerRanges {
+            all = append(all, ranges...)
+        }
+        res.CandidateConflicts = []networkRange{}
+        for _, r := range all {
+            if !isWorldCidr(r.Cidr) && cidrsOverlap(candidate, r.Cidr) {
+                res.CandidateConflicts = append(res.CandidateConflicts, r)
+            }
+        }
+        res.CandidateAvailable = len(res.CandidateConflicts) == 0
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* listSubnetRanges lists the primary and secondary ranges of every subnet of project.
+ */
+func (n *Network) listSubnetRanges(project string) ([]networkRange, error) {
+    var res []networkRange
+    err := n.client.Subnetworks.AggregatedList(project).Pages(n.context, func(list *compute.SubnetworkAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, s := range scoped.Subnetworks {
+                network := lastPathElement(s.Network)
+                res = append(res, networkRange{project, network, "subnet", s.Name, s.IpCidrRange, 0})
+                for _, sec := range s.SecondaryIpRanges {
+                    res = append(res, networkRange{project, network, "secondary", s.Name + "/" + sec.RangeName, sec.IpCidrRange, 0})
+                }
+            }
+        }
+        return nil
+    })
+    return res, err
+}
+
+/* analyzeCidrs finds the overlapping subnet ranges and the routes that can
+ * never be selected because every one of their addresses is covered by the
+ * subnet ranges and the other routes of the same network.
+ */
+func analyzeCidrs(subnets, routes []networkRange) cidrAnalysis {
+    res := cidrAnalysis{
+        Overlaps:         []rangeOverlap{},
+        ShadowedRoutes:   []shadowedRoute{},
+        PeeringConflicts: []rangeOverlap{},
+    }
+
+    for i := range subnets {
+        for j := i + 1; j < len(subnets); j++ {
+            if !cidrsOverlap(subnets[i].Cidr, subnets[j].Cidr) {
+                continue
+            }
+            reason := "different networks"
+            if subnets[i].Network == subnets[j].Network {
+                reason = "same network"
+            }
+            res.Overlaps = append(res.Overlaps, rangeOverlap{subnets[i], subnets[j], reason})
+        }
+    }
+
+    for _, r := range routes {
+        if cover := routeCover(r, subnets, routes); len(cover) > 0 {
+            res.ShadowedRoutes = append(res.ShadowedRoutes, shadowedRoute{r, cover})
+        }
+    }
+
+    return res
+}
+
+/* routeCover returns the subnet ranges and routes of the network of route r
+ * that are equal to or more specific than r and win over it, if together
+ * they cover every address of r. Subnet ranges always win. Other routes
+ * win when they have the same or a better (lower) priority, or a strictly
+ * better one for the same destination, as routes with the same destination
+ * and priority share the traffic.
+ */
+func routeCover(r networkRange, subnets, routes []networkRange) []networkRange {
+    var cover []networkRange
+    for _, s := range subnets {
+        if s.Network == r.Network && cidrContains(r.Cidr, s.Cidr) {
+            cover = append(cover, s)
+        }
+    }
+    for _, o := range routes {
+        if o.Network != r.Network || o.Name == r.Name || !cidrContains(r.Cidr, o.Cidr) {
+            continue
+        }
+        if o.Priority < r.Priority || (o.Priority == r.Priority && o.Cidr != r.Cidr) {
+            cover = append(cover, o)
+        }
+    }
+
+    var cidrs []string
+    for _, c := range cover {
+        cidrs = append(cidrs, c.Cidr)
+    }
+    if !cidrCovered(r.Cidr, cidrs) {
+        return nil
+    }
+    return cover
+}
+
+/* cidrCovered reports whether the union of ranges covers every address of cidr.
+ */
+func cidrCovered(cidr string, ranges []string) bool {
+    _, n, err := net.ParseCIDR(cidr)
+    if err != nil {
+        return false
+    }
+    var nets []*net.IPNet
+    for _, r := range ranges {
+        if _, ipnet, err := net.ParseCIDR(r); err == nil {
+            nets = append(nets, ipnet)
+        }
+    }
+    return ipNetCovered(n, nets)
+}
+
+/* ipNetCovered reports whether the union of nets covers n. A range that
+ * only partly covers n is split in halves until every half is covered.
+ */
+func ipNetCovered(n *net.IPNet, nets []*net.IPNet) bool {
+    ones, bits := n.Mask.Size()
+    inside := false
+    for _, o := range nets {
+        oOnes, oBits := o.Mask.Size()
+        if oBits != bits {
+            continue
+        }
+        if oOnes <= ones && o.Contains(n.IP) {
+            return true
+        }
+        if oOnes > ones && n.Contains(o.IP) {
+            inside = true
+        }
+    }
+    if !inside || ones == bits {
+        return false
+    }
+
+    mask := net.CIDRMask(ones + 1, bits)
+    upper := make(net.IP, len(n.IP))
+    copy(upper, n.IP)
+    upper[ones / 8] |= 0x80 >> uint(ones % 8)
+    return ipNetCovered(&net.IPNet{IP: n.IP, Mask: mask}, nets) && ipNetCovered(&net.IPNet{IP: upper, Mask: mask}, nets)
+}
+
+/* networkProjectAndName splits a network url such as
+ * ".../projects/my-project/global/networks/my-network".
+ */
+func networkProjectAndName(link string) (string, string) {
+    parts := strings.Split(link, "/")
+    project := ""
+    for i := 0; i < len(parts) - 1; i++ {
+        if parts[i] == "projects" {
+            project = parts[i + 1]
+        }
+    }
+    return project, lastPathElement(link)
+}
@@ -0,0 +1,115 @@
+package metricsexporter
+
+import (
+    "testing"
+)
+
+func TestCidrsOverlap(t *testing.T) {
+    tests := []struct {
+        a, b  string
+        want  bool
+    }{
+        {"10.0.0.0/8", "10.1.0.0/16", true},
+        {"10.1.0.0/16", "10.0.0.0/8", true},
+        {"10.0.0.0/24", "10.0.0.0/24", true},
+        {"10.0.0.0/24", "10.0.1.0/24", false},
+        {"192.168.0.0/16", "10.0.0.0/8", false},
+        {"0.0.0.0/0", "172.16.0.0/12", true},
+        {"2001:db8::/32", "2001:db8:1::/48", true},
+        {"10.0.0.0/8", "::/0", false},
+        {"invalid", "10.0.0.0/8", false},
+    }
+    for _, tt := range tests {
+        if got := cidrsOverlap(tt.a, tt.b); got != tt.want {
+            t.Errorf("cidrsOverlap(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+        }
+    }
+}
+
+func TestCidrCovered(t *testing.T) {
+    tests := []struct {
+        cidr    string
+        ranges  []string
+        want    bool
+    }{
+        {"10.0.0.0/16", nil, false},
+        {"10.0.0.0/16", []string{"10.0.0.0/16"}, true},
+        {"10.0.0.0/16", []string{"10.0.0.0/8"}, true},
+        {"10.0.0.0/16", []string{"10.0.0.0/17"}, false},
+        {"10.0.0.0/16", []string{"10.0.0.0/17", "10.0.128.0/17"}, true},
+        {"10.0.0.0/16", []string{"10.0.0.0/17", "10.0.128.0/18", "10.0.192.0/18"}, true},
+        {"10.0.0.0/16", []string{"10.0.0.0/17", "10.0.128.0/18"}, false},
+        {"10.0.0.0/16", []string{"10.1.0.0/16"}, false},
+        {"2001:db8::/32", []string{"2001:db8::/33", "2001:db8:8000::/33"}, true},
+        {"10.0.0.0/16", []string{"::/0"}, false},
+    }
+    for _, tt := range tests {
+        if got := cidrCovered(tt.cidr, tt.ranges); got != tt.want {
+            t.Errorf("cidrCovered(%s, %v) = %v, want %v", tt.cidr, tt.ranges, got, tt.want)
+        }
+    }
+}
+
+func TestAnalyzeCidrs(t *testing.T) {
+    subnets := []networkRange{
+        {"p", "vpc-a", "subnet", "a-1", "10.0.0.0/24", 0},
+        {"p", "vpc-a", "subnet", "a-2", "10.0.0.128/25", 0},
+        {"p", "vpc-b", "subnet", "b-1", "10.0.0.0/16", 0},
+        {"p", "vpc-a", "subnet", "a-3", "10.2.0.0/16", 0},
+    }
+    routes := []networkRange{
+        // Covered by the subnets a-1 and a-2.
+        {"p", "vpc-a", "route", "to-a-1", "10.0.0.0/24", 1000},
+        // Only half covered by more specific routes.
+        {"p", "vpc-a", "route", "wide", "10.1.0.0/16", 1000},
+        {"p", "vpc-a", "route", "half", "10.1.0.0/17", 1000},
+        // Covered by two more specific routes with a better priority.
+        {"p", "vpc-a", "route", "split", "10.3.0.0/16", 1000},
+        {"p", "vpc-a", "route", "split-low", "10.3.0.0/17", 500},
+        {"p", "vpc-a", "route", "split-high", "10.3.128.0/17", 500},
+        // More specific routes with a worse priority do not count.
+        {"p", "vpc-a", "route", "preferred", "10.4.0.0/16", 100},
+        {"p", "vpc-a", "route", "preferred-low", "10.4.0.0/17", 1000},
+        {"p", "vpc-a", "route", "preferred-high", "10.4.128.0/17", 1000},
+        // Same destination: a better priority wins, the same priority shares the traffic.
+        {"p", "vpc-a", "route", "backup", "10.5.0.0/16", 2000},
+        {"p", "vpc-a", "route", "primary", "10.5.0.0/16", 1000},
+        {"p", "vpc-a", "route", "ecmp-1", "10.6.0.0/16", 1000},
+        {"p", "vpc-a", "route", "ecmp-2", "10.6.0.0/16", 1000},
+        // Routes of other networks do not count.
+        {"p", "vpc-b", "route", "other-network", "10.2.0.0/24", 1000},
+    }
+    res := analyzeCidrs(subnets, routes)
+
+    overlaps := map[string]string{}
+    for _, o := range res.Overlaps {
+        overlaps[o.A.Name + "|" + o.B.Name] = o.Reason
+    }
+    wantOverlaps := map[string]string{
+        "a-1|a-2": "same network",
+        "a-1|b-1": "different networks",
+        "a-2|b-1": "different networks",
+    }
+    if len(overlaps) != len(wantOverlaps) {
+        t.Errorf("overlaps = %v, want %v", overlaps, wantOverlaps)
+    }
+    for k, v := range wantOverlaps {
+        if overlaps[k] != v {
+            t.Errorf("overlap %s = %q, want %q", k, overlaps[k], v)
+        }
+    }
+
+    shadowed := map[string]int{}
+    for _, s := range res.ShadowedRoutes {
+        shadowed[s.Route.Name] = len(s.ShadowedBy)
+    }
+    wantShadowed := map[string]int{"to-a-1": 2, "split": 2, "backup": 1}
+    if len(shadowed) != len(wantShadowed) {
+        t.Errorf("shadowed routes = %v, want %v", shadowed, wantShadowed)
+    }
+    for k, v := range wantShadowed {
+        if shadowed[k] != v {
+            t.Errorf("route %s shadowed by %d ranges, want %d", k, shadowed[k], v)
+        }
+    }
+}
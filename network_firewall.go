@@ -0,0 +1,234 @@
+package metricsexporter
+/**
+ * Helpers to reason about VPC firewall rules: protocols, port ranges,
+ * CIDR ranges and target matching.
+ *
+ * @see https://cloud.google.com/vpc/docs/firewalls#firewall_rule_components
+ **/
+
+import (
+    "net"
+    "strconv"
+    "strings"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* Protocol and ports of an allowed or denied entry of a firewall rule.
+ * An empty Ports list means all ports.
+ */
+type firewallProtoPorts struct {
+    Protocol  string
+    Ports     []string
+}
+
+/* listAllFirewalls lists every firewall rule of the project.
+ */
+func (n *Network) listAllFirewalls() ([]*compute.Firewall, error) {
+    var res []*compute.Firewall
+    err := n.client.Firewalls.List(n.Project).Pages(n.context, func(list *compute.FirewallList) error {
+        res = append(res, list.Items...)
+        return nil
+    })
+    return res, err
+}
+
+/* firewallAction returns "allow" or "deny" and the protocol entries of fw.
+ */
+func firewallAction(fw *compute.Firewall) (string, []firewallProtoPorts) {
+    var res []firewallProtoPorts
+    if len(fw.Denied) > 0 {
+        for _, d := range fw.Denied {
+            res = append(res, firewallProtoPorts{Protocol: d.IPProtocol, Ports: d.Ports})
+        }
+        return "deny", res
+    }
+    for _, a := range fw.Allowed {
+        res = append(res, firewallProtoPorts{Protocol: a.IPProtocol, Ports: a.Ports})
+    }
+    return "allow", res
+}
+
+/* firewallPeers returns the source (ingress) or destination (egress) ranges of fw.
+ */
+func firewallPeers(fw *compute.Firewall) []string {
+    if fw.Direction == "EGRESS" {
+        return fw.DestinationRanges
+    }
+    return fw.SourceRanges
+}
+
+/* parsePortRange parses a port ("22") or port range ("1000-2000").
+ */
+func parsePortRange(s string) (int, int, bool) {
+    parts := strings.SplitN(s, "-", 2)
+    lo, err := strconv.Atoi(parts[0])
+    if err != nil {
+        return 0, 0, false
+    }
+    hi := lo
+    if len(parts) == 2 {
+        hi, err = strconv.Atoi(parts[1])
+        if err != nil {
+            return 0, 0, false
+        }
+    }
+    return lo, hi, true
+}
+
+/* Protocol numbers of the protocol names firewall rules accept.
+ */
+var firewallProtocolNumbers = map[string]string{"tcp": "6", "udp": "17", "icmp": "1", "esp": "50", "ah": "51", "sctp": "132", "ipip": "94"}
+
+/* protocolMatches reports whether a rule protocol (name, number, or "all")
+ * applies to protocol.
+ */
+func protocolMatches(rule, protocol string) bool {
+    rule = strings.ToLower(rule)
+    protocol = strings.ToLower(protocol)
+    return rule == "all" || rule == protocol || firewallProtocolNumbers[rule] == protocol || firewallProtocolNumbers[protocol] == rule
+}
+
+/* portsMatch reports whether the rule ports cover port.
+ */
+func portsMatch(ports []string, port int) bool {
+    if len(ports) == 0 {
+        return true
+    }
+    for _, p := range ports {
+        if lo, hi, ok := parsePortRange(p); ok && port >= lo && port <= hi {
+            return true
+        }
+    }
+    return false
+}
+
+/* protoPortsMatch reports whether any of entries applies to protocol and port.
+ * A port of 0 matches any port (eg- for icmp).
+ */
+func protoPortsMatch(entries []firewallProtoPorts, protocol string, port int) bool {
+    for _, e := range entries {
+        if !protocolMatches(e.Protocol, protocol) {
+            continue
+        }
+        if port == 0 || portsMatch(e.Ports, port) {
+            return true
+        }
+    }
+    return false
+}
+
+/* protoPortsCover reports whether outer applies to every protocol and port of inner.
+ */
+func protoPortsCover(outer, inner []firewallProtoPorts) bool {
+    for _, in := range inner {
+        covered := false
+        for _, out := range outer {
+            if out.Protocol != "all" && (in.Protocol == "all" || !protocolMatches(out.Protocol, in.Protocol)) {
+                continue
+            }
+            if len(out.Ports) == 0 {
+                covered = true
+                break
+            }
+            if len(in.Ports) == 0 {
+                continue
+            }
+            all := true
+            for _, p := range in.Ports {
+                lo, hi, ok := parsePortRange(p)
+                if !ok || !portRangeCovered(out.Ports, lo, hi) {
+                    all = false
+                    break
+                }
+            }
+            if all {
+                covered = true
+                break
+            }
+        }
+        if !covered {
+            return false
+        }
+    }
+    return true
+}
+
+/* portRangeCovered reports whether a single entry of ports covers lo-hi.
+ */
+func portRangeCovered(ports []string, lo, hi int) bool {
+    for _, p := range ports {
+        if plo, phi, ok := parsePortRange(p); ok && plo <= lo && phi >= hi {
+            return true
+        }
+    }
+    return false
+}
+
+/* cidrContains reports whether cidr outer contains cidr inner.
+ */
+func cidrContains(outer, inner string) bool {
+    _, o, err := net.ParseCIDR(outer)
+    if err != nil {
+        return false
+    }
+    _, i, err := net.ParseCIDR(inner)
+    if err != nil {
+        return false
+    }
+    oOnes, oBits := o.Mask.Size()
+    iOnes, iBits := i.Mask.Size()
+    return oBits == iBits && oOnes <= iOnes && o.Contains(i.IP)
+}
+
+/* cidrsOverlap reports whether the cidrs a and b share any address.
+ */
+func cidrsOverlap(a, b string) bool {
+    return cidrContains(a, b) || cidrContains(b, a)
+}
+
+/* ipInRanges reports whether ip is in any of ranges.
+ */
+func ipInRanges(ip net.IP, ranges []string) bool {
+    for _, r := range ranges {
+        if _, ipnet, err := net.ParseCIDR(r); err == nil && ipnet.Contains(ip) {
+            return true
+        }
+    }
+    return false
+}
+
+/* isWorldCidr reports whether cidr is the whole IPv4 or IPv6 address space.
+ */
+func isWorldCidr(cidr string) bool {
+    return cidr == "0.0.0.0/0" || cidr == "::/0"
+}
+
+/* targetsMatch reports whether fw applies to an instance with tags and
+ * service accounts. Rules without targets apply to every instance.
+ */
+func targetsMatch(fw *compute.Firewall, tags, serviceAccounts []string) bool {
+    if len(fw.TargetTags) == 0 && len(fw.TargetServiceAccounts) == 0 {
+        return true
+    }
+    return anyCommon(fw.TargetTags, tags) || anyCommon(fw.TargetServiceAccounts, serviceAccounts)
+}
+
+/* anyCommon reports whether a and b have at least one common value.
+ */
+func anyCommon(a, b []string) bool {
+    for _, x := range a {
+        for _, y := range b {
+            if x == y {
+                return true
+            }
+        }
+    }
+    return false
+}
+
+/* lastPathElement returns the resource name at the end of a self link.
+ */
+func lastPathElement(link string) string {
+    return link[strings.LastIndex(link, "/") + 1:]
+}
@@ -0,0 +1,287 @@
+package metricsexporter
+/**
+ * Audit of the VPC firewall rules of a project for risky configurations.
+ *
+ * @see https://cloud.google.com/vpc/docs/firewalls
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+const (
+    SEVERITY_HIGH   = "HIGH"
+    SEVERITY_MEDIUM = "MEDIUM"
+    SEVERITY_LOW    = "LOW"
+)
+
+var (
+    // Ports that should never be reachable from the internet.
+    FirewallSensitivePorts = map[int]string{
+        22:    "ssh",
+        3389:  "rdp",
+        1433:  "mssql",
+        1521:  "oracle",
+        3306:  "mysql",
+        5432:  "postgresql",
+        5984:  "couchdb",
+        6379:  "redis",
+        9042:  "cassandra",
+        9200:  "elasticsearch",
+        11211: "memcached",
+        27017: "mongodb",
+    }
+)
+
+/* Risky firewall rule configuration.
+ */
+type firewallFinding struct {
+    Rule      string  `json:"rule"`
+    Network   string  `json:"network"`
+    Check     string  `json:"check"`
+    Severity  string  `json:"severity"`
+    Detail    string  `json:"detail"`
+}
+
+/* Firewall audit of a project.
+ */
+type firewallAudit struct {
+    Findings  []firewallFinding  `json:"findings"`
+    Counts    map[string]int     `json:"countsBySeverity"`
+}
+
+/* getFirewallsAudit evaluates the firewall rules of the project and the
+ * instances they target.
+ */
+func (n *Network) getFirewallsAudit() (string, error) {
+    rules, err := n.listAllFirewalls()
+    if err != nil {
+        return fmt.Sprintf("failed to list firewalls: "), err
+    }
+    instances, err := n.listAllInstances()
+    if err != nil {
+        return fmt.Sprintf("failed to list instances: "), err
+    }
+
+    res := newFirewallAudit(rules, instances)
+    if err := n.emitFirewallAudit(res); err != nil {
+        return fmt.Sprintf("failed to emit firewall audit metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* listAllInstances lists the instances of the project in every zone.
+ */
+func (n *Network) listAllInstances() ([]*compute.Instance, error) {
+    var res []*compute.Instance
+    err := n.client.Instances.AggregatedList(n.Project).Pages(n.context, func(list *compute.InstanceAggregatedList) error {
+        for _, scoped := range list.Items {
+            res = append(res, scoped.Instances...)
+        }
+        return nil
+    })
+    return res, err
+}
+
+/* newFirewallAudit runs every check on the enabled rules.
+ */
+func newFirewallAudit(rules []*compute.Firewall, instances []*compute.Instance) firewallAudit {
+    res := firewallAudit{
+        Findings: []firewallFinding{},
+        Counts:   map[string]int{SEVERITY_HIGH: 0, SEVERITY_MEDIUM: 0, SEVERITY_LOW: 0},
+    }
+    add := func(fw *compute.Firewall, check, severity, detail string) {
+        res.Findings = append(res.Findings, firewallFinding{
+            Rule:     fw.Name,
+            Network:  lastPathElement(fw.Network),
+            Check:    check,
+            Severity: severity,
+            Detail:   detail,
+        })
+        res.Counts[severity]++
+    }
+
+    // Tags and service accounts in use on each network.
+    tags := map[string]map[string]bool{}
+    accounts := map[string]map[string]bool{}
+    for _, inst := range instances {
+        for _, nic := range inst.NetworkInterfaces {
+            network := lastPathElement(nic.Network)
+            if tags[network] == nil {
+                tags[network] = map[string]bool{}
+                accounts[network] = map[string]bool{}
+            }
+            if inst.Tags != nil {
+                for _, t := range inst.Tags.Items {
+                    tags[network][t] = true
+                }
+            }
+            for _, sa := range inst.ServiceAccounts {
+                accounts[network][sa.Email] = true
+            }
+        }
+    }
+
+    for _, fw := range rules {
+        if fw.Disabled {
+            continue
+        }
+        action, entries := firewallAction(fw)
+        world := false
+        for _, r := range firewallPeers(fw) {
+            world = world || isWorldCidr(r)
+        }
+
+        if action == "allow" && fw.Direction != "EGRESS" && world {
+            var exposed []string
+            for _, port := range sortedSensitivePorts() {
+                if protoPortsMatch(entries, "tcp", port) {
+                    exposed = append(exposed, fmt.Sprintf("%d/%s", port, FirewallSensitivePorts[port]))
+                }
+            }
+            if len(exposed) > 0 {
+                add(fw, "sensitive_ports_open_to_internet", SEVERITY_HIGH,
+                    "ingress from anywhere is allowed on " + strings.Join(exposed, ", "))
+            }
+        }
+
+        if action == "allow" {
+            for _, e := range entries {
+                if e.Protocol == "all" {
+                    severity := SEVERITY_MEDIUM
+                    if world {
+                        severity = SEVERITY_HIGH
+                    }
+                    add(fw, "allow_all_protocols", severity, "all protocols and ports are allowed")
+                    break
+                }
+            }
+        }
+
+        if fw.LogConfig == nil || !fw.LogConfig.Enable {
+            add(fw, "logging_disabled", SEVERITY_LOW, "firewall rules logging is disabled")
+        }
+
+        for _, other := range rules {
+            if other != fw && !other.Disabled && firewallShadows(other, fw) {
+                severity := SEVERITY_LOW
+                if otherAction, _ := firewallAction(other); otherAction != action {
+                    severity = SEVERITY_MEDIUM
+                }
+                add(fw, "shadowed", severity, fmt.Sprintf("rule never takes effect because of higher priority rule %s", other.Name))
+                break
+            }
+        }
+
+        if len(fw.TargetTags) > 0 || len(fw.TargetServiceAccounts) > 0 {
+            network := lastPathElement(fw.Network)
+            used := false
+            for _, t := range fw.TargetTags {
+                used = used || tags[network][t]
+            }
+            for _, sa := range fw.TargetServiceAccounts {
+                used = used || accounts[network][sa]
+            }
+            if !used {
+                add(fw, "unused_targets", SEVERITY_LOW, "target tags and service accounts match no instance")
+            }
+        }
+    }
+
+    return res
+}
+
+/* firewallShadows reports whether the higher priority rule s matches all
+ * the traffic of rule r, so that r is never evaluated.
+ */
+func firewallShadows(s, r *compute.Firewall) bool {
+    if s.Network != r.Network || s.Direction != r.Direction {
+        return false
+    }
+    sAction, sEntries := firewallAction(s)
+    rAction, rEntries := firewallAction(r)
+    // Deny rules win over allow rules of the same priority.
+    if s.Priority > r.Priority || (s.Priority == r.Priority && !(sAction == "deny" && rAction == "allow")) {
+        return false
+    }
+
+    if len(s.TargetTags) > 0 || len(s.TargetServiceAccounts) > 0 {
+        if len(r.TargetTags) == 0 && len(r.TargetServiceAccounts) == 0 {
+            return false
+        }
+        if !isSubset(r.TargetTags, s.TargetTags) || !isSubset(r.TargetServiceAccounts, s.TargetServiceAccounts) {
+            return false
+        }
+    }
+
+    sPeers := firewallPeers(s)
+    sWorld := false
+    for _, p := range sPeers {
+        sWorld = sWorld || isWorldCidr(p)
+    }
+    for _, rp := range firewallPeers(r) {
+        covered := false
+        for _, sp := range sPeers {
+            covered = covered || cidrContains(sp, rp)
+        }
+        if !covered {
+            return false
+        }
+    }
+    if !sWorld && (!isSubset(r.SourceTags, s.SourceTags) || !isSubset(r.SourceServiceAccounts, s.SourceServiceAccounts)) {
+        return false
+    }
+
+    return protoPortsCover(sEntries, rEntries)
+}
+
+/* isSubset reports whether every value of a is in b.
+ */
+func isSubset(a, b []string) bool {
+    for _, x := range a {
+        found := false
+        for _, y := range b {
+            found = found || x == y
+        }
+        if !found {
+            return false
+        }
+    }
+    return true
+}
+
+/* sortedSensitivePorts returns the sensitive ports in ascending order.
+ */
+func sortedSensitivePorts() []int {
+    var res []int
+    for p := range FirewallSensitivePorts {
+        res = append(res, p)
+    }
+    sort.Ints(res)
+    return res
+}
+
+/* emitFirewallAudit sends the number of findings per severity as metrics.
+ */
+func (n *Network) emitFirewallAudit(audit firewallAudit) error {
+    if !n.EnableEmitter {
+        return nil
+    }
+
+    count := newGaugeVec("gcp_firewall_audit_findings", "Number of risky firewall rule findings per severity.", "project", "severity")
+    for severity, c := range audit.Counts {
+        count.WithLabelValues(n.Project, severity).Set(float64(c))
+    }
+
+    return emitCollectors(n.emitter, "network.firewalls.audit", n.Project, count)
+}
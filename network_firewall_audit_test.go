@@ -0,0 +1,111 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* firewallRule returns an ingress rule of the network "vpc" from sources on the
+ * tcp ports, allowed unless deny is set.
+ */
+func firewallRule(name string, priority int64, deny bool, sources []string, ports ...string) *compute.Firewall {
+    fw := &compute.Firewall{
+        Name:         name,
+        Network:      "https://www.googleapis.com/compute/v1/projects/p/global/networks/vpc",
+        Direction:    "INGRESS",
+        Priority:     priority,
+        SourceRanges: sources,
+        LogConfig:    &compute.FirewallLogConfig{Enable: true},
+    }
+    if deny {
+        fw.Denied = []*compute.FirewallDenied{{IPProtocol: "tcp", Ports: ports}}
+    } else {
+        fw.Allowed = []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: ports}}
+    }
+    return fw
+}
+
+func TestFirewallShadows(t *testing.T) {
+    internal := []string{"10.0.0.0/8"}
+    withTargets := func(fw *compute.Firewall, tags ...string) *compute.Firewall {
+        fw.TargetTags = tags
+        return fw
+    }
+    tests := []struct {
+        name  string
+        s, r  *compute.Firewall
+        want  bool
+    }{
+        {"equal priority, deny wins over allow", firewallRule("s", 1000, true, internal, "22"), firewallRule("r", 1000, false, internal, "22"), true},
+        {"equal priority, allow does not win over deny", firewallRule("s", 1000, false, internal, "22"), firewallRule("r", 1000, true, internal, "22"), false},
+        {"equal priority, same action", firewallRule("s", 1000, false, internal, "22"), firewallRule("r", 1000, false, internal, "22"), false},
+        {"lower priority", firewallRule("s", 2000, true, internal, "22"), firewallRule("r", 1000, false, internal, "22"), false},
+        {"narrower source range", firewallRule("s", 100, false, internal, "22"), firewallRule("r", 1000, false, []string{"10.1.0.0/16"}, "22"), true},
+        {"wider source range", firewallRule("s", 100, false, []string{"10.1.0.0/16"}, "22"), firewallRule("r", 1000, false, internal, "22"), false},
+        {"ports covered by a range", firewallRule("s", 100, false, internal, "1-1024"), firewallRule("r", 1000, false, internal, "22", "80"), true},
+        {"ports not covered", firewallRule("s", 100, false, internal, "22"), firewallRule("r", 1000, false, internal, "22", "80"), false},
+        {"target tag subset", withTargets(firewallRule("s", 100, true, internal, "22"), "web", "db"), withTargets(firewallRule("r", 1000, false, internal, "22"), "web"), true},
+        {"target tag superset", withTargets(firewallRule("s", 100, true, internal, "22"), "web"), withTargets(firewallRule("r", 1000, false, internal, "22"), "web", "db"), false},
+        {"targets all instances", withTargets(firewallRule("s", 100, true, internal, "22"), "web"), firewallRule("r", 1000, false, internal, "22"), false},
+        {"applies to all instances", firewallRule("s", 100, true, internal, "22"), withTargets(firewallRule("r", 1000, false, internal, "22"), "web"), true},
+    }
+    for _, tt := range tests {
+        if got := firewallShadows(tt.s, tt.r); got != tt.want {
+            t.Errorf("%s: firewallShadows() = %v, want %v", tt.name, got, tt.want)
+        }
+    }
+
+    other := firewallRule("s", 100, true, internal, "22")
+    other.Network = "https://www.googleapis.com/compute/v1/projects/p/global/networks/other"
+    if firewallShadows(other, firewallRule("r", 1000, false, internal, "22")) {
+        t.Errorf("firewallShadows() across networks = true, want false")
+    }
+}
+
+func TestNewFirewallAudit(t *testing.T) {
+    world := []string{"0.0.0.0/0"}
+    internal := []string{"10.0.0.0/8"}
+
+    ssh := firewallRule("ssh", 1000, false, world, "22", "443")
+    all := firewallRule("all", 1000, false, internal)
+    all.Allowed[0].IPProtocol = "all"
+    all.LogConfig = nil
+    tagged := firewallRule("tagged", 1000, false, internal, "8080")
+    tagged.TargetTags = []string{"missing"}
+    denied := firewallRule("denied", 900, true, internal, "80")
+    shadowed := firewallRule("shadowed", 1000, false, []string{"10.1.0.0/16"}, "80")
+    // A disabled rule has no findings and shadows no rule.
+    disabled := firewallRule("disabled", 100, true, world, "22", "443", "8080")
+    disabled.Disabled = true
+    disabled.LogConfig = nil
+
+    instances := []*compute.Instance{{
+        Tags:              &compute.Tags{Items: []string{"web"}},
+        NetworkInterfaces: []*compute.NetworkInterface{{Network: ssh.Network}},
+    }}
+    res := newFirewallAudit([]*compute.Firewall{ssh, all, tagged, denied, shadowed, disabled}, instances)
+
+    got := map[string]string{}
+    for _, f := range res.Findings {
+        got[f.Rule + "/" + f.Check] = f.Severity
+    }
+    want := map[string]string{
+        "ssh/sensitive_ports_open_to_internet": SEVERITY_HIGH,
+        "all/allow_all_protocols":              SEVERITY_MEDIUM,
+        "all/logging_disabled":                 SEVERITY_LOW,
+        "tagged/unused_targets":                SEVERITY_LOW,
+        "shadowed/shadowed":                    SEVERITY_MEDIUM,
+    }
+    if len(got) != len(want) {
+        t.Errorf("findings = %v, want %v", got, want)
+    }
+    for k, v := range want {
+        if got[k] != v {
+            t.Errorf("finding %s = %q, want %q", k, got[k], v)
+        }
+    }
+    if res.Counts[SEVERITY_HIGH] != 1 || res.Counts[SEVERITY_MEDIUM] != 2 || res.Counts[SEVERITY_LOW] != 2 {
+        t.Errorf("counts = %v", res.Counts)
+    }
+}
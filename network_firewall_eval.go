@@ -0,0 +1,206 @@
+package metricsexporter
+/**
+ * Effective firewall evaluation: works out whether a packet to or from an
+ * instance is allowed by the VPC firewall rules, and which rules matched.
+ *
+ * @see https://cloud.google.com/vpc/docs/firewalls#priority_order_for_firewall_rules
+ **/
+
+import (
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net"
+    "sort"
+    "strconv"
+    "strings"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+const (
+    FIREWALL_IMPLIED_PRIORITY = 65535
+)
+
+// Protocols whose rules can list ports, by name and number.
+var firewallPortProtocols = map[string]bool{"tcp": true, "udp": true, "sctp": true, "6": true, "17": true, "132": true}
+
+/* Packet evaluated against the firewall rules.
+ */
+type firewallPacket struct {
+    Network          string    `json:"network"`
+    Instance         string    `json:"instance,omitempty"`
+    Tags             []string  `json:"tags,omitempty"`
+    ServiceAccounts  []string  `json:"serviceAccounts,omitempty"`
+    Direction        string    `json:"direction"`
+    Protocol         string    `json:"protocol"`
+    Port             int       `json:"port,omitempty"`
+    Cidr             string    `json:"cidr"`
+}
+
+/* Firewall rule matching the packet.
+ */
+type firewallMatch struct {
+    Rule       string  `json:"rule"`
+    Priority   int64   `json:"priority"`
+    Action     string  `json:"action"`
+    Effective  bool    `json:"effective"`
+}
+
+/* Verdict of a firewall evaluation.
+ */
+type firewallVerdict struct {
+    Packet       firewallPacket   `json:"packet"`
+    Verdict      string           `json:"verdict"`
+    MatchedRule  string           `json:"matchedRule"`
+    Chain        []firewallMatch  `json:"chain"`
+}
+
+/* newFirewallPacket reads the packet to evaluate from qry. The network
+ * is taken from the namespace, and the direction defaults to ingress.
+ * The protocol is a name or a number. The port must be between 1 and
+ * 65535, and is required by the protocols with ports (tcp, udp and sctp).
+ */
+func newFirewallPacket(qry Query) (firewallPacket, error) {
+    p := firewallPacket{
+        Network:   qry.Namespace,
+        Instance:  qry.Instance,
+        Direction: strings.ToUpper(qry.Direction),
+        Protocol:  strings.ToLower(qry.Protocol),
+        Cidr:      qry.Cidr,
+    }
+    if p.Direction == "" {
+        p.Direction = "INGRESS"
+    }
+    if qry.Tags != "" {
+        p.Tags = strings.Split(qry.Tags, ",")
+    }
+    if qry.ServiceAccount != "" {
+        p.ServiceAccounts = []string{qry.ServiceAccount}
+    }
+    if _, known := firewallProtocolNumbers[p.Protocol]; !known {
+        if n, err := strconv.Atoi(p.Protocol); err != nil || n < 0 || n > 255 {
+            return p, fmt.Errorf("invalid protocol %q", qry.Protocol)
+        }
+    }
+    if qry.Port != "" {
+        port, err := strconv.Atoi(qry.Port)
+        if err != nil || port < 1 || port > 65535 {
+            return p, fmt.Errorf("invalid port %q", qry.Port)
+        }
+        p.Port = port
+    } else if firewallPortProtocols[p.Protocol] {
+        return p, fmt.Errorf("port is required for protocol %q", qry.Protocol)
+    }
+    // A single address is evaluated as a /32 (or /128) range.
+    if ip := net.ParseIP(p.Cidr); ip != nil {
+        if ip.To4() != nil {
+            p.Cidr = p.Cidr + "/32"
+        } else {
+            p.Cidr = p.Cidr + "/128"
+        }
+    }
+    return p, nil
+}
+
+/* getFirewallsEvaluate evaluates the firewall rules for packet. If an instance
+ * is set, its network, tags and service accounts are looked up in the zone.
+ */
+func (n *Network) getFirewallsEvaluate(packet firewallPacket) (string, error) {
+    if packet.Direction != "INGRESS" && packet.Direction != "EGRESS" {
+        return fmt.Sprintf("invalid direction: "), errors.New(packet.Direction)
+    }
+    if _, _, err := net.ParseCIDR(packet.Cidr); err != nil {
+        return fmt.Sprintf("invalid cidr: "), err
+    }
+
+    if packet.Instance != "" {
+        inst, err := n.client.Instances.Get(n.Project, n.Zone, packet.Instance).Do()
+        if err != nil {
+            return fmt.Sprintf("failed to get instance: "), err
+        }
+        if len(inst.NetworkInterfaces) > 0 {
+            packet.Network = lastPathElement(inst.NetworkInterfaces[0].Network)
+        }
+        if inst.Tags != nil {
+            packet.Tags = inst.Tags.Items
+        }
+        packet.ServiceAccounts = nil
+        for _, sa := range inst.ServiceAccounts {
+            packet.ServiceAccounts = append(packet.ServiceAccounts, sa.Email)
+        }
+    }
+
+    rules, err := n.listAllFirewalls()
+    if err != nil {
+        return fmt.Sprintf("failed to list firewalls: "), err
+    }
+
+    json, err := json.MarshalIndent(evaluateFirewalls(rules, packet), "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* evaluateFirewalls returns the verdict for packet. Rules are evaluated in
+ * priority order, deny before allow, and the first matching rule wins.
+ * The implied rules (deny all ingress, allow all egress) match last.
+ * The packet source is a range, not an instance, so ingress rules that
+ * match only on source tags or source service accounts never match.
+ */
+func evaluateFirewalls(rules []*compute.Firewall, packet firewallPacket) firewallVerdict {
+    var matching []*compute.Firewall
+    for _, fw := range rules {
+        if fw.Disabled || lastPathElement(fw.Network) != packet.Network || fw.Direction != packet.Direction {
+            continue
+        }
+        if !targetsMatch(fw, packet.Tags, packet.ServiceAccounts) {
+            continue
+        }
+        covered := false
+        for _, r := range firewallPeers(fw) {
+            covered = covered || cidrContains(r, packet.Cidr)
+        }
+        if !covered {
+            continue
+        }
+        if _, entries := firewallAction(fw); !protoPortsMatch(entries, packet.Protocol, packet.Port) {
+            continue
+        }
+        matching = append(matching, fw)
+    }
+
+    sort.SliceStable(matching, func(i, j int) bool {
+        if matching[i].Priority != matching[j].Priority {
+            return matching[i].Priority < matching[j].Priority
+        }
+        ai, _ := firewallAction(matching[i])
+        aj, _ := firewallAction(matching[j])
+        return ai == "deny" && aj == "allow"
+    })
+
+    res := firewallVerdict{
+        Packet: packet,
+        Chain:  []firewallMatch{},
+    }
+    for _, fw := range matching {
+        action, _ := firewallAction(fw)
+        res.Chain = append(res.Chain, firewallMatch{
+            Rule:     fw.Name,
+            Priority: fw.Priority,
+            Action:   action,
+        })
+    }
+
+    implied := firewallMatch{Rule: "implied-deny-ingress", Priority: FIREWALL_IMPLIED_PRIORITY, Action: "deny"}
+    if packet.Direction == "EGRESS" {
+        implied = firewallMatch{Rule: "implied-allow-egress", Priority: FIREWALL_IMPLIED_PRIORITY, Action: "allow"}
+    }
+    res.Chain = append(res.Chain, implied)
+
+    res.Chain[0].Effective = true
+    res.MatchedRule = res.Chain[0].Rule
+    res.Verdict = strings.ToUpper(res.Chain[0].Action)
+    return res
+}
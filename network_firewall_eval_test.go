@@ -0,0 +1,133 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestNewFirewallPacket(t *testing.T) {
+    tests := []struct {
+        protocol  string
+        port      string
+        cidr      string
+        wantErr   bool
+        wantPort  int
+        wantCidr  string
+    }{
+        {"tcp", "443", "10.0.0.1", false, 443, "10.0.0.1/32"},
+        {"TCP", "80", "10.0.0.0/8", false, 80, "10.0.0.0/8"},
+        {"TCP", "", "10.0.0.0/8", true, 0, ""},
+        {"17", "", "10.0.0.0/8", true, 0, ""},
+        {"sctp", "", "10.0.0.0/8", true, 0, ""},
+        {"udp", "65535", "2001:db8::1", false, 65535, "2001:db8::1/128"},
+        {"icmp", "", "0.0.0.0/0", false, 0, "0.0.0.0/0"},
+        {"6", "22", "0.0.0.0/0", false, 22, "0.0.0.0/0"},
+        {"tcp", "0", "0.0.0.0/0", true, 0, ""},
+        {"tcp", "65536", "0.0.0.0/0", true, 0, ""},
+        {"tcp", "-1", "0.0.0.0/0", true, 0, ""},
+        {"tcp", "http", "0.0.0.0/0", true, 0, ""},
+        {"", "80", "0.0.0.0/0", true, 0, ""},
+        {"all", "80", "0.0.0.0/0", true, 0, ""},
+        {"gre2", "", "0.0.0.0/0", true, 0, ""},
+        {"256", "", "0.0.0.0/0", true, 0, ""},
+    }
+    for _, tt := range tests {
+        p, err := newFirewallPacket(Query{Namespace: "default", Protocol: tt.protocol, Port: tt.port, Cidr: tt.cidr})
+        if (err != nil) != tt.wantErr {
+            t.Errorf("newFirewallPacket(%q, %q) error = %v, want error %v", tt.protocol, tt.port, err, tt.wantErr)
+            continue
+        }
+        if err != nil {
+            continue
+        }
+        if p.Port != tt.wantPort || p.Cidr != tt.wantCidr || p.Direction != "INGRESS" {
+            t.Errorf("newFirewallPacket(%q, %q) = %+v", tt.protocol, tt.port, p)
+        }
+    }
+}
+
+func TestEvaluateFirewalls(t *testing.T) {
+    network := "https://www.googleapis.com/compute/v1/projects/p/global/networks/default"
+    rules := []*compute.Firewall{
+        {
+            Name: "allow-ssh", Network: network, Direction: "INGRESS", Priority: 1000,
+            SourceRanges: []string{"0.0.0.0/0"},
+            Allowed:      []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"22"}}},
+        },
+        {
+            Name: "deny-ssh-office", Network: network, Direction: "INGRESS", Priority: 1000,
+            SourceRanges: []string{"192.168.0.0/16"},
+            Denied:       []*compute.FirewallDenied{{IPProtocol: "tcp", Ports: []string{"22"}}},
+        },
+        {
+            Name: "allow-web", Network: network, Direction: "INGRESS", Priority: 900,
+            SourceRanges: []string{"0.0.0.0/0"}, TargetTags: []string{"web"},
+            Allowed:      []*compute.FirewallAllowed{{IPProtocol: "tcp", Ports: []string{"80", "8000-8999"}}},
+        },
+        {
+            Name: "allow-web-disabled", Network: network, Direction: "INGRESS", Priority: 100, Disabled: true,
+            SourceRanges: []string{"0.0.0.0/0"},
+            Allowed:      []*compute.FirewallAllowed{{IPProtocol: "all"}},
+        },
+        {
+            Name: "deny-egress-db", Network: network, Direction: "EGRESS", Priority: 1000,
+            DestinationRanges: []string{"10.1.0.0/16"},
+            Denied:            []*compute.FirewallDenied{{IPProtocol: "tcp", Ports: []string{"5432"}}},
+        },
+    }
+
+    tests := []struct {
+        name         string
+        packet       firewallPacket
+        verdict      string
+        matchedRule  string
+        chainLength  int
+    }{
+        {
+            "ssh from anywhere",
+            firewallPacket{Network: "default", Direction: "INGRESS", Protocol: "tcp", Port: 22, Cidr: "8.8.8.8/32"},
+            "ALLOW", "allow-ssh", 2,
+        },
+        {
+            "deny wins over allow at the same priority",
+            firewallPacket{Network: "default", Direction: "INGRESS", Protocol: "tcp", Port: 22, Cidr: "192.168.1.1/32"},
+            "DENY", "deny-ssh-office", 3,
+        },
+        {
+            "web port range with tag",
+            firewallPacket{Network: "default", Direction: "INGRESS", Protocol: "tcp", Port: 8080, Cidr: "8.8.8.8/32", Tags: []string{"web"}},
+            "ALLOW", "allow-web", 2,
+        },
+        {
+            "web port without tag",
+            firewallPacket{Network: "default", Direction: "INGRESS", Protocol: "tcp", Port: 8080, Cidr: "8.8.8.8/32"},
+            "DENY", "implied-deny-ingress", 1,
+        },
+        {
+            "other network",
+            firewallPacket{Network: "other", Direction: "INGRESS", Protocol: "tcp", Port: 22, Cidr: "8.8.8.8/32"},
+            "DENY", "implied-deny-ingress", 1,
+        },
+        {
+            "egress denied",
+            firewallPacket{Network: "default", Direction: "EGRESS", Protocol: "tcp", Port: 5432, Cidr: "10.1.2.3/32"},
+            "DENY", "deny-egress-db", 2,
+        },
+        {
+            "egress allowed by the implied rule",
+            firewallPacket{Network: "default", Direction: "EGRESS", Protocol: "udp", Port: 53, Cidr: "10.1.2.3/32"},
+            "ALLOW", "implied-allow-egress", 1,
+        },
+    }
+    for _, tt := range tests {
+        res := evaluateFirewalls(rules, tt.packet)
+        if res.Verdict != tt.verdict || res.MatchedRule != tt.matchedRule || len(res.Chain) != tt.chainLength {
+            t.Errorf("%s: got %s by %s with %d matches, want %s by %s with %d matches", tt.name,
+                res.Verdict, res.MatchedRule, len(res.Chain), tt.verdict, tt.matchedRule, tt.chainLength)
+        }
+        if len(res.Chain) > 0 && !res.Chain[0].Effective {
+            t.Errorf("%s: the first match is not effective", tt.name)
+        }
+    }
+}
@@ -0,0 +1,246 @@
+package metricsexporter
+/**
+ * Health of the hybrid connectivity of a region: Cloud VPN tunnels,
+ * HA VPN gateways and interconnect attachments (VLANs).
+ *
+ * @see https://cloud.google.com/network-connectivity/docs/vpn/how-to/checking-vpn-status
+ * @see https://cloud.google.com/network-connectivity/docs/interconnect/how-to/monitoring
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* Status of a VPN tunnel.
+ */
+type vpnTunnelStatus struct {
+    Name            string  `json:"name"`
+    Status          string  `json:"status"`
+    DetailedStatus  string  `json:"detailedStatus"`
+    PeerIp          string  `json:"peerIp"`
+    Gateway         string  `json:"gateway"`
+    Router          string  `json:"router,omitempty"`
+    Up              bool    `json:"up"`
+}
+
+/* Status of a connection of an HA VPN gateway to a peer gateway.
+ */
+type vpnConnectionStatus struct {
+    Gateway            string    `json:"gateway"`
+    PeerGateway        string    `json:"peerGateway"`
+    State              string    `json:"state"`
+    UnsatisfiedReason  string    `json:"unsatisfiedReason,omitempty"`
+    Tunnels            []string  `json:"tunnels"`
+}
+
+/* Status of an interconnect attachment.
+ */
+type interconnectAttachmentStatus struct {
+    Name               string  `json:"name"`
+    Type               string  `json:"type"`
+    State              string  `json:"state"`
+    OperationalStatus  string  `json:"operationalStatus"`
+    Bandwidth          string  `json:"bandwidth"`
+    AdminEnabled       bool    `json:"adminEnabled"`
+    Interconnect       string  `json:"interconnect,omitempty"`
+    Router             string  `json:"router"`
+    Up                 bool    `json:"up"`
+}
+
+/* @see https://cloud.google.com/compute/docs/reference/rest/v1/vpnTunnels/list
+ */
+func (n *Network) getVpnTunnelsList() (string, error) {
+    list, err := n.client.VpnTunnels.List(n.Project, n.Region).Do()
+    if err != nil {
+        return fmt.Sprintf("failed to list vpn tunnels: "), err
+    }
+
+    res := []vpnTunnelStatus{}
+    for _, t := range list.Items {
+        res = append(res, newVpnTunnelStatus(t))
+    }
+
+    if err := n.emitVpnTunnels(res); err != nil {
+        return fmt.Sprintf("failed to emit vpn tunnel metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newVpnTunnelStatus converts tunnel t. Classic VPN tunnels belong to a
+ * target VPN gateway, HA VPN tunnels to a VPN gateway.
+ */
+func newVpnTunnelStatus(t *compute.VpnTunnel) vpnTunnelStatus {
+    gateway := t.VpnGateway
+    if gateway == "" {
+        gateway = t.TargetVpnGateway
+    }
+    return vpnTunnelStatus{
+        Name:           t.Name,
+        Status:         t.Status,
+        DetailedStatus: t.DetailedStatus,
+        PeerIp:         t.PeerIp,
+        Gateway:        lastPathElement(gateway),
+        Router:         lastPathElement(t.Router),
+        Up:             t.Status == "ESTABLISHED",
+    }
+}
+
+/* getVpnGatewaysStatus reports the high availability status of the HA VPN
+ * gateway named in Arg1, or of every HA VPN gateway in the region.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/vpnGateways/getStatus
+ */
+func (n *Network) getVpnGatewaysStatus(gateway string) (string, error) {
+    var gateways []string
+    if gateway != "" {
+        gateways = append(gateways, gateway)
+    } else {
+        list, err := n.client.VpnGateways.List(n.Project, n.Region).Do()
+        if err != nil {
+            return fmt.Sprintf("failed to list vpn gateways: "), err
+        }
+        for _, g := range list.Items {
+            gateways = append(gateways, g.Name)
+        }
+    }
+
+    res := []vpnConnectionStatus{}
+    for _, g := range gateways {
+        status, err := n.client.VpnGateways.GetStatus(n.Project, n.Region, g).Do()
+        if err != nil {
+            return fmt.Sprintf("failed to get vpn gateway status: "), err
+        }
+        if status.Result == nil {
+            continue
+        }
+        for _, c := range status.Result.VpnConnections {
+            res = append(res, newVpnConnectionStatus(g, c))
+        }
+    }
+
+    if err := n.emitVpnGateways(gateway, res); err != nil {
+        return fmt.Sprintf("failed to emit vpn gateway metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newVpnConnectionStatus converts the connection c of the HA VPN gateway.
+ * The peer is either another HA VPN gateway or an external VPN gateway.
+ */
+func newVpnConnectionStatus(gateway string, c *compute.VpnGatewayStatusVpnConnection) vpnConnectionStatus {
+    res := vpnConnectionStatus{
+        Gateway: gateway,
+        Tunnels: []string{},
+    }
+    if c.PeerGcpGateway != "" {
+        res.PeerGateway = lastPathElement(c.PeerGcpGateway)
+    } else {
+        res.PeerGateway = lastPathElement(c.PeerExternalGateway)
+    }
+    if c.State != nil {
+        res.State = c.State.State
+        res.UnsatisfiedReason = c.State.UnsatisfiedReason
+    }
+    for _, t := range c.Tunnels {
+        res.Tunnels = append(res.Tunnels, lastPathElement(t.TunnelUrl))
+    }
+    return res
+}
+
+/* @see https://cloud.google.com/compute/docs/reference/rest/v1/interconnectAttachments/list
+ */
+func (n *Network) getInterconnectAttachmentsList() (string, error) {
+    list, err := n.client.InterconnectAttachments.List(n.Project, n.Region).Do()
+    if err != nil {
+        return fmt.Sprintf("failed to list interconnect attachments: "), err
+    }
+
+    res := []interconnectAttachmentStatus{}
+    for _, a := range list.Items {
+        res = append(res, interconnectAttachmentStatus{
+            Name:              a.Name,
+            Type:              a.Type,
+            State:             a.State,
+            OperationalStatus: a.OperationalStatus,
+            Bandwidth:         a.Bandwidth,
+            AdminEnabled:      a.AdminEnabled,
+            Interconnect:      lastPathElement(a.Interconnect),
+            Router:            lastPathElement(a.Router),
+            Up:                a.OperationalStatus == "OS_ACTIVE" && a.State == "ACTIVE",
+        })
+    }
+
+    if err := n.emitInterconnectAttachments(res); err != nil {
+        return fmt.Sprintf("failed to emit interconnect attachment metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* emitVpnTunnels sends whether every VPN tunnel is established as metrics.
+ */
+func (n *Network) emitVpnTunnels(tunnels []vpnTunnelStatus) error {
+    if !n.EnableEmitter {
+        return nil
+    }
+
+    up := newGaugeVec("gcp_vpn_tunnel_up", "Whether the VPN tunnel is established.", "project", "region", "tunnel", "gateway")
+    for _, t := range tunnels {
+        up.WithLabelValues(n.Project, n.Region, t.Name, t.Gateway).Set(boolToFloat(t.Up))
+    }
+
+    return emitCollectors(n.emitter, "network.vpntunnels.list." + n.Region, n.Project, up)
+}
+
+/* emitVpnGateways sends whether every HA VPN gateway connection meets the
+ * high availability requirements as metrics, grouped by the queried gateway
+ * if any so that it does not replace the metrics of the whole region.
+ */
+func (n *Network) emitVpnGateways(gateway string, connections []vpnConnectionStatus) error {
+    if !n.EnableEmitter {
+        return nil
+    }
+
+    ha := newGaugeVec("gcp_vpn_gateway_ha_satisfied", "Whether the connection of the HA VPN gateway meets the 99.99% availability requirements.", "project", "region", "gateway", "peer_gateway")
+    for _, c := range connections {
+        ha.WithLabelValues(n.Project, n.Region, c.Gateway, c.PeerGateway).Set(boolToFloat(c.State == "CONNECTION_REDUNDANCY_MET"))
+    }
+
+    target := "network.vpngateways.getstatus." + n.Region
+    if gateway != "" {
+        target = target + "." + gateway
+    }
+    return emitCollectors(n.emitter, target, n.Project, ha)
+}
+
+/* emitInterconnectAttachments sends whether every interconnect attachment
+ * is active as metrics.
+ */
+func (n *Network) emitInterconnectAttachments(attachments []interconnectAttachmentStatus) error {
+    if !n.EnableEmitter {
+        return nil
+    }
+
+    up := newGaugeVec("gcp_interconnect_attachment_up", "Whether the interconnect attachment is active.", "project", "region", "attachment", "bandwidth")
+    for _, a := range attachments {
+        up.WithLabelValues(n.Project, n.Region, a.Name, a.Bandwidth).Set(boolToFloat(a.Up))
+    }
+
+    return emitCollectors(n.emitter, "network.interconnectattachments.list." + n.Region, n.Project, up)
+}
@@ -0,0 +1,33 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestNewVpnConnectionStatus(t *testing.T) {
+    prefix := COMPUTE_API_PREFIX + "projects/p/"
+    tests := []struct {
+        name  string
+        conn  *compute.VpnGatewayStatusVpnConnection
+        want  string
+    }{
+        {"gcp peer", &compute.VpnGatewayStatusVpnConnection{PeerGcpGateway: prefix + "regions/us-central1/vpnGateways/peer"}, "peer"},
+        {"external peer", &compute.VpnGatewayStatusVpnConnection{PeerExternalGateway: prefix + "global/externalVpnGateways/onprem"}, "onprem"},
+        {"no peer", &compute.VpnGatewayStatusVpnConnection{}, ""},
+    }
+    for _, tt := range tests {
+        if got := newVpnConnectionStatus("gw", tt.conn); got.PeerGateway != tt.want || got.Gateway != "gw" {
+            t.Errorf("%s: newVpnConnectionStatus() = %+v, want peer %q", tt.name, got, tt.want)
+        }
+    }
+
+    c := newVpnConnectionStatus("gw", &compute.VpnGatewayStatusVpnConnection{
+        State:   &compute.VpnGatewayStatusHighAvailabilityRequirementState{State: "CONNECTION_REDUNDANCY_NOT_MET", UnsatisfiedReason: "INCOMPLETE_TUNNELS_COVERAGE"},
+        Tunnels: []*compute.VpnGatewayStatusTunnel{{TunnelUrl: prefix + "regions/us-central1/vpnTunnels/t1"}},
+    })
+    if c.State != "CONNECTION_REDUNDANCY_NOT_MET" || c.UnsatisfiedReason != "INCOMPLETE_TUNNELS_COVERAGE" || len(c.Tunnels) != 1 || c.Tunnels[0] != "t1" {
+        t.Errorf("newVpnConnectionStatus() = %+v", c)
+    }
+}
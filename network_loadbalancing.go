@@ -0,0 +1,301 @@
+package metricsexporter
+/**
+ * Load balancing inventory (forwarding rules, backend services, URL maps,
+ * target proxies and health checks) and backend health.
+ *
+ * Global and regional resources are listed together.
+ *
+ * @see https://cloud.google.com/load-balancing/docs/load-balancing-overview
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* Health of a backend group of a backend service.
+ */
+type lbBackendHealth struct {
+    BackendService  string              `json:"backendService"`
+    Region          string              `json:"region,omitempty"`
+    Group           string              `json:"group"`
+    Healthy         int                 `json:"healthy"`
+    Total           int                 `json:"total"`
+    Endpoints       []lbEndpointHealth  `json:"endpoints"`
+    Error           string              `json:"error,omitempty"`
+}
+
+/* Health of a single instance or endpoint of a backend group.
+ */
+type lbEndpointHealth struct {
+    Instance     string  `json:"instance,omitempty"`
+    IpAddress    string  `json:"ipAddress"`
+    Port         int64   `json:"port"`
+    HealthState  string  `json:"healthState"`
+}
+
+/* indentJSON returns the indented json of the API resource m.
+ */
+func indentJSON(m json.Marshaler) (string, error) {
+    bt, err := m.MarshalJSON()
+    if err != nil {
+        return "", err
+    }
+    rw := json.RawMessage(bt)
+    json, _ := json.MarshalIndent(rw, "", "\t")
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* getForwardingRulesList lists the global and regional forwarding rules.
+ * Rules returned by both lists are only reported once.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/forwardingRules/aggregatedList
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/globalForwardingRules/list
+ */
+func (n *Network) getForwardingRulesList() (string, error) {
+    var items []json.Marshaler
+    seen := map[string]bool{}
+    add := func(v *compute.ForwardingRule) {
+        if !seen[v.SelfLink] {
+            seen[v.SelfLink] = true
+            items = append(items, v)
+        }
+    }
+    err := n.client.GlobalForwardingRules.List(n.Project).Pages(n.context, func(list *compute.ForwardingRuleList) error {
+        for _, v := range list.Items {
+            add(v)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list global forwarding rules: "), err
+    }
+    err = n.client.ForwardingRules.AggregatedList(n.Project).Pages(n.context, func(list *compute.ForwardingRuleAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, v := range scoped.ForwardingRules {
+                add(v)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list forwarding rules: "), err
+    }
+    return joinIndentJSON(items)
+}
+
+/* @see https://cloud.google.com/compute/docs/reference/rest/v1/backendServices/aggregatedList
+ */
+func (n *Network) getBackendServicesList() (string, error) {
+    services, err := n.listAllBackendServices()
+    if err != nil {
+        return fmt.Sprintf("failed to list backend services: "), err
+    }
+    var items []json.Marshaler
+    for _, v := range services {
+        items = append(items, v)
+    }
+    return joinIndentJSON(items)
+}
+
+/* @see https://cloud.google.com/compute/docs/reference/rest/v1/urlMaps/aggregatedList
+ */
+func (n *Network) getUrlMapsList() (string, error) {
+    var items []json.Marshaler
+    err := n.client.UrlMaps.AggregatedList(n.Project).Pages(n.context, func(list *compute.UrlMapsAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, v := range scoped.UrlMaps {
+                items = append(items, v)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list url maps: "), err
+    }
+    return joinIndentJSON(items)
+}
+
+/* getTargetProxiesList lists the HTTP, HTTPS, SSL, TCP and gRPC target
+ * proxies. The aggregated lists of the HTTP and HTTPS proxies include
+ * both the global and the regional ones.
+ * @see https://cloud.google.com/load-balancing/docs/target-proxies
+ */
+func (n *Network) getTargetProxiesList() (string, error) {
+    var items []json.Marshaler
+    err := n.client.TargetHttpProxies.AggregatedList(n.Project).Pages(n.context, func(list *compute.TargetHttpProxyAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, v := range scoped.TargetHttpProxies {
+                items = append(items, v)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list target http proxies: "), err
+    }
+    err = n.client.TargetHttpsProxies.AggregatedList(n.Project).Pages(n.context, func(list *compute.TargetHttpsProxyAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, v := range scoped.TargetHttpsProxies {
+                items = append(items, v)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list target https proxies: "), err
+    }
+    err = n.client.TargetSslProxies.List(n.Project).Pages(n.context, func(list *compute.TargetSslProxyList) error {
+        for _, v := range list.Items {
+            items = append(items, v)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list target ssl proxies: "), err
+    }
+    err = n.client.TargetTcpProxies.List(n.Project).Pages(n.context, func(list *compute.TargetTcpProxyList) error {
+        for _, v := range list.Items {
+            items = append(items, v)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list target tcp proxies: "), err
+    }
+    err = n.client.TargetGrpcProxies.List(n.Project).Pages(n.context, func(list *compute.TargetGrpcProxyList) error {
+        for _, v := range list.Items {
+            items = append(items, v)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list target grpc proxies: "), err
+    }
+    return joinIndentJSON(items)
+}
+
+/* @see https://cloud.google.com/compute/docs/reference/rest/v1/healthChecks/aggregatedList
+ */
+func (n *Network) getHealthChecksList() (string, error) {
+    var items []json.Marshaler
+    err := n.client.HealthChecks.AggregatedList(n.Project).Pages(n.context, func(list *compute.HealthChecksAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, v := range scoped.HealthChecks {
+                items = append(items, v)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list health checks: "), err
+    }
+    return joinIndentJSON(items)
+}
+
+/* getBackendsHealth reports the health of every backend group of every
+ * backend service, global or regional.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/backendServices/getHealth
+ */
+func (n *Network) getBackendsHealth() (string, error) {
+    services, err := n.listAllBackendServices()
+    if err != nil {
+        return fmt.Sprintf("failed to list backend services: "), err
+    }
+
+    res := []lbBackendHealth{}
+    for _, bs := range services {
+        region := lastPathElement(bs.Region)
+        for _, b := range bs.Backends {
+            ref := &compute.ResourceGroupReference{Group: b.Group}
+            var health *compute.BackendServiceGroupHealth
+            if region != "" {
+                health, err = n.client.RegionBackendServices.GetHealth(n.Project, region, bs.Name, ref).Context(n.context).Do()
+            } else {
+                health, err = n.client.BackendServices.GetHealth(n.Project, bs.Name, ref).Context(n.context).Do()
+            }
+            // Some groups, such as serverless NEGs, have no health to report.
+            res = append(res, newLbBackendHealth(bs.Name, region, b.Group, health, err))
+        }
+    }
+
+    if n.EnableEmitter {
+        labels := []string{"project", "backend_service", "region", "group"}
+        healthy := newGaugeVec("gcp_lb_backend_healthy", "Number of healthy endpoints in the backend group.", labels...)
+        total := newGaugeVec("gcp_lb_backend_total", "Number of endpoints in the backend group.", labels...)
+        for _, bh := range res {
+            if bh.Error != "" {
+                continue
+            }
+            healthy.WithLabelValues(n.Project, bh.BackendService, bh.Region, bh.Group).Set(float64(bh.Healthy))
+            total.WithLabelValues(n.Project, bh.BackendService, bh.Region, bh.Group).Set(float64(bh.Total))
+        }
+        if err := emitCollectors(n.emitter, "network.backends.health", n.Project, healthy, total); err != nil {
+            return fmt.Sprintf("failed to emit backend health metrics: "), err
+        }
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newLbBackendHealth summarizes the health of the group of backend service
+ * bs. err is the error of the health request, if any.
+ */
+func newLbBackendHealth(bs, region, group string, health *compute.BackendServiceGroupHealth, err error) lbBackendHealth {
+    res := lbBackendHealth{
+        BackendService: bs,
+        Region:         region,
+        Group:          lastPathElement(group),
+        Endpoints:      []lbEndpointHealth{},
+    }
+    if err != nil {
+        res.Error = err.Error()
+        return res
+    }
+    for _, hs := range health.HealthStatus {
+        res.Endpoints = append(res.Endpoints, lbEndpointHealth{
+            Instance:    lastPathElement(hs.Instance),
+            IpAddress:   hs.IpAddress,
+            Port:        hs.Port,
+            HealthState: hs.HealthState,
+        })
+        if hs.HealthState == "HEALTHY" {
+            res.Healthy++
+        }
+        res.Total++
+    }
+    return res
+}
+
+/* listAllBackendServices lists the global and regional backend services.
+ */
+func (n *Network) listAllBackendServices() ([]*compute.BackendService, error) {
+    var res []*compute.BackendService
+    err := n.client.BackendServices.AggregatedList(n.Project).Pages(n.context, func(list *compute.BackendServiceAggregatedList) error {
+        for _, scoped := range list.Items {
+            res = append(res, scoped.BackendServices...)
+        }
+        return nil
+    })
+    return res, err
+}
+
+/* joinIndentJSON concatenates the indented json of every item, like the
+ * other list targets.
+ */
+func joinIndentJSON(items []json.Marshaler) (string, error) {
+    var res string
+    for _, v := range items {
+        s, err := indentJSON(v)
+        if err != nil {
+            return "", err
+        }
+        res = res + s
+    }
+    return res, nil
+}
@@ -0,0 +1,51 @@
+package metricsexporter
+
+import (
+    "errors"
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestNewLbBackendHealth(t *testing.T) {
+    group := COMPUTE_API_PREFIX + "projects/p/zones/us-central1-a/instanceGroups/ig"
+    tests := []struct {
+        name     string
+        health   *compute.BackendServiceGroupHealth
+        err      error
+        healthy  int
+        total    int
+        errMsg   string
+    }{
+        {"no endpoints", &compute.BackendServiceGroupHealth{}, nil, 0, 0, ""},
+        {
+            "mixed health",
+            &compute.BackendServiceGroupHealth{
+                HealthStatus: []*compute.HealthStatus{
+                    {Instance: group + "/vm-1", IpAddress: "10.0.0.1", Port: 80, HealthState: "HEALTHY"},
+                    {Instance: group + "/vm-2", IpAddress: "10.0.0.2", Port: 80, HealthState: "UNHEALTHY"},
+                    {IpAddress: "10.0.0.3", Port: 80, HealthState: "HEALTHY"},
+                },
+            },
+            nil, 2, 3, "",
+        },
+        {"serverless neg", nil, errors.New("not supported"), 0, 0, "not supported"},
+    }
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            res := newLbBackendHealth("bs", "", group, tt.health, tt.err)
+            if res.Group != "ig" || res.BackendService != "bs" {
+                t.Errorf("got backend %s group %s, want bs ig", res.BackendService, res.Group)
+            }
+            if res.Healthy != tt.healthy || res.Total != tt.total {
+                t.Errorf("got %d/%d healthy, want %d/%d", res.Healthy, res.Total, tt.healthy, tt.total)
+            }
+            if len(res.Endpoints) != tt.total {
+                t.Errorf("got %d endpoints, want %d", len(res.Endpoints), tt.total)
+            }
+            if res.Error != tt.errMsg {
+                t.Errorf("got error %q, want %q", res.Error, tt.errMsg)
+            }
+        })
+    }
+}
@@ -17,6 +17,7 @@ type NetworkBuilder interface {
     Context(context.Context)  NetworkBuilder
     Project(string)           NetworkBuilder
     Region(string)            NetworkBuilder
+    Zone(string)              NetworkBuilder
     EnableEmitter()           NetworkBuilder
 
     Build()                   (Network, error)
@@ -26,6 +27,7 @@ type networkBuild struct {
     context        context.Context
     project        string
     region         string
+    zone           string
     enableemitter  bool
 }
 
@@ -37,6 +39,7 @@ type Network struct {
     client         *compute.Service
     Project        string
     Region         string
+    Zone           string
     EnableEmitter  bool
     emitter        Emitters
 }
@@ -69,6 +72,13 @@ func (b *networkBuild) Region(region string) NetworkBuilder {
 	return b
 }
 
+/* Zone is the GCP zone of the instances the request refers to.
+ */
+func (b *networkBuild) Zone(zone string) NetworkBuilder {
+	b.zone = zone
+	return b
+}
+
 /*
  */
 func (b *networkBuild) EnableEmitter() NetworkBuilder {
@@ -95,6 +105,7 @@ func (b *networkBuild) Build() (Network, error) {
         client:         client,
         Project:        b.project,
         Region:         b.region,
+        Zone:           b.zone,
         EnableEmitter:  b.enableemitter,
         emitter:        pusher,
     }, nil
@@ -120,6 +131,50 @@ func (n *Network) Do(qry Query) (string, error) {
         return n.getRoutesList()
     } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "interconnects.list" {
         return n.getInterconnectsList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "firewalls.audit" {
+        return n.getFirewallsAudit()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "firewalls.evaluate" {
+        packet, err := newFirewallPacket(qry)
+        if err != nil {
+            return fmt.Sprintf("invalid packet: "), err
+        }
+        return n.getFirewallsEvaluate(packet)
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "cidrs.analyze" {
+        return n.getCidrsAnalysis(qry.Arg1)
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "topology" {
+        return n.getTopology(qry.Arg1)
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "routers.status" {
+        return n.getRoutersStatus()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "vpntunnels.list" {
+        return n.getVpnTunnelsList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "vpngateways.getstatus" {
+        return n.getVpnGatewaysStatus(qry.Arg1)
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "interconnectattachments.list" {
+        return n.getInterconnectAttachmentsList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "forwardingrules.list" {
+        return n.getForwardingRulesList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "backendservices.list" {
+        return n.getBackendServicesList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "urlmaps.list" {
+        return n.getUrlMapsList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "targetproxies.list" {
+        return n.getTargetProxiesList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "healthchecks.list" {
+        return n.getHealthChecksList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "backends.health" {
+        return n.getBackendsHealth()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "sslcertificates.list" {
+        return n.getSslCertificatesList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "peerings.list" {
+        return n.getPeeringsList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "xpnresources.list" {
+        return n.getXpnResourcesList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "usablesubnets.list" {
+        return n.getUsableSubnetsList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "securitypolicies.list" {
+        return n.getSecurityPoliciesList()
+    } else if qry.Resource == "network" && qry.Action == "get" && qry.Target == "waste" {
+        return n.getWaste()
     }
     return "[Debug] It will call some Compute operations to return json response", nil
 }
@@ -0,0 +1,185 @@
+package metricsexporter
+/**
+ * Runtime status of the Cloud Routers of a region: BGP sessions,
+ * learned routes and Cloud NAT allocation.
+ *
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/routers/getRouterStatus
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/routers/getNatMappingInfo
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+const (
+    // Ports allocated to each VM when the NAT config leaves minPortsPerVm unset.
+    NAT_DEFAULT_MIN_PORTS_PER_VM = 64
+)
+
+/* Status of a BGP session.
+ */
+type routerBgpPeer struct {
+    Name            string  `json:"name"`
+    Status          string  `json:"status"`
+    State           string  `json:"state"`
+    LearnedRoutes   int64   `json:"learnedRoutes"`
+    UptimeSeconds   string  `json:"uptimeSeconds,omitempty"`
+    PeerIpAddress   string  `json:"peerIpAddress"`
+    LinkedVpnTunnel string  `json:"linkedVpnTunnel,omitempty"`
+}
+
+/* Status of a Cloud NAT gateway.
+ */
+type routerNat struct {
+    Name                  string  `json:"name"`
+    NatIps                int     `json:"natIps"`
+    MinPortsPerVm         int64   `json:"minPortsPerVm"`
+    MinExtraNatIpsNeeded  int64   `json:"minExtraNatIpsNeeded"`
+    VmEndpoints           int64   `json:"vmEndpointsWithNatMappings"`
+}
+
+/* Status of a Cloud Router.
+ */
+type routerStatus struct {
+    Name           string            `json:"name"`
+    Network        string            `json:"network"`
+    BestRoutes     int               `json:"bestRoutes"`
+    BgpPeers       []routerBgpPeer   `json:"bgpPeers"`
+    Nats           []routerNat       `json:"nats"`
+    NatPortsPerVm  map[string]int64  `json:"natPortsPerVm,omitempty"`
+}
+
+/* getRoutersStatus reports the status of every router in the region.
+ */
+func (n *Network) getRoutersStatus() (string, error) {
+    list, err := n.client.Routers.List(n.Project, n.Region).Do()
+    if err != nil {
+        return fmt.Sprintf("failed to list routers: "), err
+    }
+
+    var res []routerStatus
+    for _, r := range list.Items {
+        status, err := n.client.Routers.GetRouterStatus(n.Project, n.Region, r.Name).Do()
+        if err != nil {
+            return fmt.Sprintf("failed to get router status: "), err
+        }
+        rs := newRouterStatus(r, status.Result)
+
+        if len(r.Nats) > 0 {
+            // Ports allocated to each VM, across all the NAT gateways of the router.
+            rs.NatPortsPerVm = map[string]int64{}
+            err = n.client.Routers.GetNatMappingInfo(n.Project, n.Region, r.Name).Pages(n.context, func(m *compute.VmEndpointNatMappingsList) error {
+                for _, vm := range m.Result {
+                    for _, nic := range vm.InterfaceNatMappings {
+                        rs.NatPortsPerVm[vm.InstanceName] += nic.NumTotalNatPorts
+                    }
+                }
+                return nil
+            })
+            if err != nil {
+                return fmt.Sprintf("failed to get nat mapping info: "), err
+            }
+        }
+        res = append(res, rs)
+    }
+
+    if err := n.emitRoutersStatus(res); err != nil {
+        return fmt.Sprintf("failed to emit router metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newRouterStatus combines the configuration and runtime status of router r.
+ */
+func newRouterStatus(r *compute.Router, status *compute.RouterStatus) routerStatus {
+    res := routerStatus{
+        Name:     r.Name,
+        Network:  lastPathElement(r.Network),
+        BgpPeers: []routerBgpPeer{},
+        Nats:     []routerNat{},
+    }
+    if status == nil {
+        return res
+    }
+    res.BestRoutes = len(status.BestRoutes)
+
+    for _, p := range status.BgpPeerStatus {
+        res.BgpPeers = append(res.BgpPeers, routerBgpPeer{
+            Name:            p.Name,
+            Status:          p.Status,
+            State:           p.State,
+            LearnedRoutes:   p.NumLearnedRoutes,
+            UptimeSeconds:   p.UptimeSeconds,
+            PeerIpAddress:   p.PeerIpAddress,
+            LinkedVpnTunnel: lastPathElement(p.LinkedVpnTunnel),
+        })
+    }
+
+    minPorts := map[string]int64{}
+    for _, nat := range r.Nats {
+        minPorts[nat.Name] = nat.MinPortsPerVm
+    }
+    for _, s := range status.NatStatus {
+        ports := minPorts[s.Name]
+        if ports == 0 {
+            ports = NAT_DEFAULT_MIN_PORTS_PER_VM
+        }
+        res.Nats = append(res.Nats, routerNat{
+            Name:                 s.Name,
+            NatIps:               len(s.AutoAllocatedNatIps) + len(s.UserAllocatedNatIps),
+            MinPortsPerVm:        ports,
+            MinExtraNatIpsNeeded: s.MinExtraNatIpsNeeded,
+            VmEndpoints:          s.NumVmEndpointsWithNatMappings,
+        })
+    }
+    return res
+}
+
+/* emitRoutersStatus sends the BGP and NAT status of every router as metrics.
+ */
+func (n *Network) emitRoutersStatus(routers []routerStatus) error {
+    if !n.EnableEmitter {
+        return nil
+    }
+
+    peerLabels := []string{"project", "region", "router", "peer"}
+    peerUp := newGaugeVec("gcp_router_bgp_peer_up", "Whether the BGP session is up.", peerLabels...)
+    learned := newGaugeVec("gcp_router_bgp_learned_routes", "Number of routes learned from the BGP peer.", peerLabels...)
+
+    natLabels := []string{"project", "region", "router", "nat"}
+    natIps := newGaugeVec("gcp_router_nat_ips", "Number of IPs allocated to the NAT gateway.", natLabels...)
+    extraIps := newGaugeVec("gcp_router_nat_min_extra_ips_needed", "Number of extra IPs needed to serve all VMs (port exhaustion if above 0).", natLabels...)
+    endpoints := newGaugeVec("gcp_router_nat_vm_endpoints", "Number of VM endpoints with NAT mappings.", natLabels...)
+    minPorts := newGaugeVec("gcp_router_nat_min_ports_per_vm", "Minimum number of ports allocated to each VM.", natLabels...)
+    vmPorts := newGaugeVec("gcp_router_nat_vm_allocated_ports", "Number of NAT ports allocated to the VM.", "project", "region", "router", "instance")
+
+    for _, r := range routers {
+        for _, p := range r.BgpPeers {
+            up := 0.0
+            if p.Status == "UP" {
+                up = 1
+            }
+            peerUp.WithLabelValues(n.Project, n.Region, r.Name, p.Name).Set(up)
+            learned.WithLabelValues(n.Project, n.Region, r.Name, p.Name).Set(float64(p.LearnedRoutes))
+        }
+        for _, nat := range r.Nats {
+            natIps.WithLabelValues(n.Project, n.Region, r.Name, nat.Name).Set(float64(nat.NatIps))
+            extraIps.WithLabelValues(n.Project, n.Region, r.Name, nat.Name).Set(float64(nat.MinExtraNatIpsNeeded))
+            endpoints.WithLabelValues(n.Project, n.Region, r.Name, nat.Name).Set(float64(nat.VmEndpoints))
+            minPorts.WithLabelValues(n.Project, n.Region, r.Name, nat.Name).Set(float64(nat.MinPortsPerVm))
+        }
+        for vm, ports := range r.NatPortsPerVm {
+            vmPorts.WithLabelValues(n.Project, n.Region, r.Name, vm).Set(float64(ports))
+        }
+    }
+
+    return emitCollectors(n.emitter, "network.routers.status." + n.Region, n.Project, peerUp, learned, natIps, extraIps, endpoints, minPorts, vmPorts)
+}
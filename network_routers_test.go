@@ -0,0 +1,62 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestNewRouterStatus(t *testing.T) {
+    prefix := COMPUTE_API_PREFIX + "projects/p/"
+    router := &compute.Router{
+        Name:    "r",
+        Network: prefix + "global/networks/vpc",
+        Nats: []*compute.RouterNat{
+            {Name: "nat-default"},
+            {Name: "nat-tuned", MinPortsPerVm: 1024},
+        },
+    }
+    tests := []struct {
+        name      string
+        status    *compute.RouterStatus
+        peers     int
+        nats      map[string]routerNat
+    }{
+        {"no status", nil, 0, map[string]routerNat{}},
+        {
+            "bgp and nat",
+            &compute.RouterStatus{
+                BestRoutes: []*compute.Route{{}, {}},
+                BgpPeerStatus: []*compute.RouterStatusBgpPeerStatus{
+                    {Name: "peer", Status: "UP", NumLearnedRoutes: 3, LinkedVpnTunnel: prefix + "regions/us-central1/vpnTunnels/t1"},
+                },
+                NatStatus: []*compute.RouterStatusNatStatus{
+                    {Name: "nat-default", AutoAllocatedNatIps: []string{"a", "b"}, NumVmEndpointsWithNatMappings: 4},
+                    {Name: "nat-tuned", UserAllocatedNatIps: []string{"c"}, MinExtraNatIpsNeeded: 2},
+                },
+            },
+            1,
+            map[string]routerNat{
+                "nat-default": {Name: "nat-default", NatIps: 2, MinPortsPerVm: NAT_DEFAULT_MIN_PORTS_PER_VM, VmEndpoints: 4},
+                "nat-tuned":   {Name: "nat-tuned", NatIps: 1, MinPortsPerVm: 1024, MinExtraNatIpsNeeded: 2},
+            },
+        },
+    }
+    for _, tt := range tests {
+        got := newRouterStatus(router, tt.status)
+        if got.Name != "r" || got.Network != "vpc" || len(got.BgpPeers) != tt.peers || len(got.Nats) != len(tt.nats) {
+            t.Errorf("%s: newRouterStatus() = %+v", tt.name, got)
+            continue
+        }
+        for _, nat := range got.Nats {
+            if nat != tt.nats[nat.Name] {
+                t.Errorf("%s: nat = %+v, want %+v", tt.name, nat, tt.nats[nat.Name])
+            }
+        }
+        if tt.peers > 0 {
+            if p := got.BgpPeers[0]; p.LearnedRoutes != 3 || p.LinkedVpnTunnel != "t1" || got.BestRoutes != 2 {
+                t.Errorf("%s: bgp peer = %+v, best routes = %d", tt.name, p, got.BestRoutes)
+            }
+        }
+    }
+}
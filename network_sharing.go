@@ -0,0 +1,339 @@
+package metricsexporter
+/**
+ * Sharing of the VPC networks of a project with other projects, through
+ * VPC Network Peering and Shared VPC.
+ *
+ * @see https://cloud.google.com/vpc/docs/vpc-peering
+ * @see https://cloud.google.com/vpc/docs/shared-vpc
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+    "strconv"
+    "strings"
+
+    cloudresourcemanager  "google.golang.org/api/cloudresourcemanager/v1"
+    compute               "google.golang.org/api/compute/v1"
+)
+
+const (
+    // Role that allows a member to use a shared subnet.
+    XPN_NETWORK_USER_ROLE = "roles/compute.networkUser"
+)
+
+/* Peering of a local network with a peer network.
+ */
+type vpcPeering struct {
+    Network                         string  `json:"network"`
+    Name                            string  `json:"name"`
+    PeerProject                     string  `json:"peerProject"`
+    PeerNetwork                     string  `json:"peerNetwork"`
+    State                           string  `json:"state"`
+    StateDetails                    string  `json:"stateDetails,omitempty"`
+    ExchangeSubnetRoutes            bool    `json:"exchangeSubnetRoutes"`
+    ExportCustomRoutes              bool    `json:"exportCustomRoutes"`
+    ImportCustomRoutes              bool    `json:"importCustomRoutes"`
+    ExportSubnetRoutesWithPublicIp  bool    `json:"exportSubnetRoutesWithPublicIp"`
+    ImportSubnetRoutesWithPublicIp  bool    `json:"importSubnetRoutesWithPublicIp"`
+}
+
+/* Subnet usable by the caller, including the subnets shared by a host project.
+ */
+type usableSubnet struct {
+    Project          string             `json:"project"`
+    Network          string             `json:"network"`
+    Subnetwork       string             `json:"subnetwork"`
+    IpCidrRange      string             `json:"ipCidrRange"`
+    SecondaryRanges  map[string]string  `json:"secondaryRanges,omitempty"`
+}
+
+/* Service project attached to a Shared VPC host project.
+ */
+type xpnServiceProject struct {
+    Project         string             `json:"project"`
+    ProjectNumber   int64              `json:"projectNumber,omitempty"`
+    AllSubnets      bool               `json:"allSubnets"`
+    ProjectMembers  []string           `json:"projectMembers"`
+    Subnets         []xpnSubnetAccess  `json:"subnets"`
+    Error           string             `json:"error,omitempty"`
+}
+
+/* Shared subnet and the members granted the network user role on it.
+ */
+type xpnSubnetAccess struct {
+    Subnetwork  string    `json:"subnetwork"`
+    Region      string    `json:"region"`
+    Network     string    `json:"network"`
+    Members     []string  `json:"members"`
+}
+
+/* Network user binding that belongs to no attached service project.
+ * The subnetwork is empty for the bindings of the host project.
+ */
+type xpnBinding struct {
+    Subnetwork  string  `json:"subnetwork,omitempty"`
+    Region      string  `json:"region,omitempty"`
+    Member      string  `json:"member"`
+}
+
+/* Service projects of a Shared VPC host project and the subnets they can use.
+ */
+type xpnAccessReport struct {
+    HostProject      string               `json:"hostProject"`
+    ServiceProjects  []xpnServiceProject  `json:"serviceProjects"`
+    Unattributed     []xpnBinding         `json:"unattributed"`
+}
+
+/* getPeeringsList lists the peerings of every network of the project.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/networks/list
+ */
+func (n *Network) getPeeringsList() (string, error) {
+    networks, err := n.listAllNetworks()
+    if err != nil {
+        return fmt.Sprintf("failed to list networks: "), err
+    }
+
+    res := []vpcPeering{}
+    for _, nw := range networks {
+        for _, p := range nw.Peerings {
+            project, network := networkProjectAndName(p.Network)
+            res = append(res, vpcPeering{
+                Network:                        nw.Name,
+                Name:                           p.Name,
+                PeerProject:                    project,
+                PeerNetwork:                    network,
+                State:                          p.State,
+                StateDetails:                   p.StateDetails,
+                ExchangeSubnetRoutes:           p.ExchangeSubnetRoutes,
+                ExportCustomRoutes:             p.ExportCustomRoutes,
+                ImportCustomRoutes:             p.ImportCustomRoutes,
+                ExportSubnetRoutesWithPublicIp: p.ExportSubnetRoutesWithPublicIp,
+                ImportSubnetRoutesWithPublicIp: p.ImportSubnetRoutesWithPublicIp,
+            })
+        }
+    }
+
+    if n.EnableEmitter {
+        active := newGaugeVec("gcp_vpc_peering_active", "Whether the network peering is active.", "project", "network", "peering", "peer_project", "peer_network")
+        for _, p := range res {
+            active.WithLabelValues(n.Project, p.Network, p.Name, p.PeerProject, p.PeerNetwork).Set(boolToFloat(p.State == "ACTIVE"))
+        }
+        if err := emitCollectors(n.emitter, "network.peerings.list", n.Project, active); err != nil {
+            return fmt.Sprintf("failed to emit peering metrics: "), err
+        }
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* getXpnResourcesList maps the service projects attached to the project,
+ * when it is a Shared VPC host project, to the shared subnets they can use.
+ * A service project can use every subnet of the host project when one of
+ * its members is granted roles/compute.networkUser on the host project, or
+ * only the subnets whose IAM policy grants the role to one of its members.
+ * Members that do not belong to an attached service project (users, groups,
+ * service accounts of other projects) are reported as unattributed.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/projects/getXpnResources
+ * @see https://cloud.google.com/vpc/docs/shared-vpc#iam_in_shared_vpc
+ */
+func (n *Network) getXpnResourcesList() (string, error) {
+    var services []xpnServiceProject
+    err := n.client.Projects.GetXpnResources(n.Project).Pages(n.context, func(list *compute.ProjectsGetXpnResources) error {
+        for _, r := range list.Resources {
+            if r.Type == "PROJECT" {
+                services = append(services, xpnServiceProject{Project: r.Id})
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to get xpn resources: "), err
+    }
+
+    crm, err := cloudresourcemanager.NewService(n.context)
+    if err != nil {
+        return fmt.Sprintf("failed to create resource manager client: "), err
+    }
+
+    // The default service accounts of a project are named after its number.
+    for i := range services {
+        p, err := crm.Projects.Get(services[i].Project).Context(n.context).Do()
+        if err != nil {
+            services[i].Error = err.Error()
+            continue
+        }
+        services[i].ProjectNumber = p.ProjectNumber
+    }
+
+    policy, err := crm.Projects.GetIamPolicy(n.Project, &cloudresourcemanager.GetIamPolicyRequest{}).Context(n.context).Do()
+    if err != nil {
+        return fmt.Sprintf("failed to get project iam policy: "), err
+    }
+    var projectMembers []string
+    for _, b := range policy.Bindings {
+        if b.Role == XPN_NETWORK_USER_ROLE {
+            projectMembers = append(projectMembers, b.Members...)
+        }
+    }
+
+    var subnets []xpnSubnetAccess
+    err = n.client.Subnetworks.AggregatedList(n.Project).Pages(n.context, func(list *compute.SubnetworkAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, s := range scoped.Subnetworks {
+                region := lastPathElement(s.Region)
+                policy, err := n.client.Subnetworks.GetIamPolicy(n.Project, region, s.Name).Context(n.context).Do()
+                if err != nil {
+                    return err
+                }
+                sa := xpnSubnetAccess{Subnetwork: s.Name, Region: region, Network: lastPathElement(s.Network)}
+                for _, b := range policy.Bindings {
+                    if b.Role == XPN_NETWORK_USER_ROLE {
+                        sa.Members = append(sa.Members, b.Members...)
+                    }
+                }
+                subnets = append(subnets, sa)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to get subnetwork iam policies: "), err
+    }
+
+    res := newXpnAccessReport(n.Project, services, projectMembers, subnets)
+
+    if n.EnableEmitter {
+        usable := newGaugeVec("gcp_shared_vpc_service_project_subnets", "Number of shared subnets the service project can use.", "project", "service_project")
+        for _, s := range res.ServiceProjects {
+            usable.WithLabelValues(n.Project, s.Project).Set(float64(len(s.Subnets)))
+        }
+        if err := emitCollectors(n.emitter, "network.xpnresources.list", n.Project, usable); err != nil {
+            return fmt.Sprintf("failed to emit shared vpc metrics: "), err
+        }
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* newXpnAccessReport attributes the networkUser members of the host project
+ * and of its subnets to the attached service projects.
+ */
+func newXpnAccessReport(host string, services []xpnServiceProject, projectMembers []string, subnets []xpnSubnetAccess) xpnAccessReport {
+    res := xpnAccessReport{
+        HostProject:     host,
+        ServiceProjects: []xpnServiceProject{},
+        Unattributed:    []xpnBinding{},
+    }
+    attributed := map[string]bool{}
+
+    for _, s := range services {
+        s.ProjectMembers = []string{}
+        s.Subnets = []xpnSubnetAccess{}
+        for _, m := range projectMembers {
+            if memberOfProject(m, s.Project, s.ProjectNumber) {
+                s.ProjectMembers = append(s.ProjectMembers, m)
+                attributed[m] = true
+            }
+        }
+        s.AllSubnets = len(s.ProjectMembers) > 0
+        for _, sub := range subnets {
+            access := xpnSubnetAccess{Subnetwork: sub.Subnetwork, Region: sub.Region, Network: sub.Network, Members: []string{}}
+            for _, m := range sub.Members {
+                if memberOfProject(m, s.Project, s.ProjectNumber) {
+                    access.Members = append(access.Members, m)
+                    attributed[sub.Region + "/" + sub.Subnetwork + "|" + m] = true
+                }
+            }
+            if s.AllSubnets || len(access.Members) > 0 {
+                s.Subnets = append(s.Subnets, access)
+            }
+        }
+        res.ServiceProjects = append(res.ServiceProjects, s)
+    }
+
+    for _, m := range projectMembers {
+        if !attributed[m] {
+            res.Unattributed = append(res.Unattributed, xpnBinding{Member: m})
+        }
+    }
+    for _, sub := range subnets {
+        for _, m := range sub.Members {
+            if !attributed[sub.Region + "/" + sub.Subnetwork + "|" + m] {
+                res.Unattributed = append(res.Unattributed, xpnBinding{Subnetwork: sub.Subnetwork, Region: sub.Region, Member: m})
+            }
+        }
+    }
+    return res
+}
+
+/* memberOfProject tells whether the IAM member is a service account of the
+ * project: a user-managed service account created in the project, or one
+ * of its default service accounts (Compute Engine, Google APIs, GKE, ...),
+ * which are named after the project number.
+ */
+func memberOfProject(member, projectId string, projectNumber int64) bool {
+    if !strings.HasPrefix(member, "serviceAccount:") {
+        return false
+    }
+    email := strings.TrimPrefix(member, "serviceAccount:")
+    at := strings.Index(email, "@")
+    if at < 0 {
+        return false
+    }
+    local, domain := email[:at], email[at + 1:]
+    if domain == projectId + ".iam.gserviceaccount.com" {
+        return true
+    }
+    if projectNumber == 0 {
+        return false
+    }
+    number := strconv.FormatInt(projectNumber, 10)
+    return local == number || local == number + "-compute" || local == "service-" + number
+}
+
+/* getUsableSubnetsList lists the subnets the caller can use, either in
+ * the project or shared with it by a host project. The list depends on the
+ * credentials of the exporter, not on the permissions of the project: use
+ * xpnresources.list on the host project to audit the service projects.
+ * @see https://cloud.google.com/compute/docs/reference/rest/v1/subnetworks/listUsable
+ */
+func (n *Network) getUsableSubnetsList() (string, error) {
+    res := []usableSubnet{}
+    err := n.client.Subnetworks.ListUsable(n.Project).Pages(n.context, func(list *compute.UsableSubnetworksAggregatedList) error {
+        for _, s := range list.Items {
+            project, network := networkProjectAndName(s.Network)
+            us := usableSubnet{
+                Project:     project,
+                Network:     network,
+                Subnetwork:  lastPathElement(s.Subnetwork),
+                IpCidrRange: s.IpCidrRange,
+            }
+            if len(s.SecondaryIpRanges) > 0 {
+                us.SecondaryRanges = map[string]string{}
+                for _, r := range s.SecondaryIpRanges {
+                    us.SecondaryRanges[r.RangeName] = r.IpCidrRange
+                }
+            }
+            res = append(res, us)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list usable subnetworks: "), err
+    }
+
+    json, err := json.MarshalIndent(res, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
@@ -0,0 +1,79 @@
+package metricsexporter
+
+import (
+    "testing"
+)
+
+func TestMemberOfProject(t *testing.T) {
+    tests := []struct {
+        member  string
+        want    bool
+    }{
+        {"serviceAccount:app@svc-a.iam.gserviceaccount.com", true},
+        {"serviceAccount:123-compute@developer.gserviceaccount.com", true},
+        {"serviceAccount:123@cloudservices.gserviceaccount.com", true},
+        {"serviceAccount:service-123@container-engine-robot.iam.gserviceaccount.com", true},
+        {"serviceAccount:app@svc-b.iam.gserviceaccount.com", false},
+        {"serviceAccount:456-compute@developer.gserviceaccount.com", false},
+        {"user:123@example.com", false},
+        {"group:svc-a@example.com", false},
+    }
+    for _, tt := range tests {
+        if got := memberOfProject(tt.member, "svc-a", 123); got != tt.want {
+            t.Errorf("memberOfProject(%s) = %v, want %v", tt.member, got, tt.want)
+        }
+    }
+    if memberOfProject("serviceAccount:123-compute@developer.gserviceaccount.com", "svc-a", 0) {
+        t.Errorf("memberOfProject() matched a project without a number")
+    }
+}
+
+func TestNewXpnAccessReport(t *testing.T) {
+    services := []xpnServiceProject{
+        {Project: "svc-a", ProjectNumber: 1},
+        {Project: "svc-b", ProjectNumber: 2},
+        {Project: "svc-c", ProjectNumber: 3},
+    }
+    projectMembers := []string{
+        "serviceAccount:1-compute@developer.gserviceaccount.com",
+        "group:network-admins@example.com",
+    }
+    subnets := []xpnSubnetAccess{
+        {Subnetwork: "web", Region: "us-central1", Network: "shared", Members: []string{
+            "serviceAccount:app@svc-b.iam.gserviceaccount.com",
+            "user:alice@example.com",
+        }},
+        {Subnetwork: "db", Region: "us-central1", Network: "shared"},
+    }
+    res := newXpnAccessReport("host", services, projectMembers, subnets)
+
+    want := map[string]struct {
+        all      bool
+        subnets  int
+    }{
+        "svc-a": {true, 2},
+        "svc-b": {false, 1},
+        "svc-c": {false, 0},
+    }
+    if len(res.ServiceProjects) != len(want) {
+        t.Fatalf("got %d service projects, want %d", len(res.ServiceProjects), len(want))
+    }
+    for _, s := range res.ServiceProjects {
+        if w := want[s.Project]; s.AllSubnets != w.all || len(s.Subnets) != w.subnets {
+            t.Errorf("service project %s = %+v, want all subnets %v and %d subnets", s.Project, s, w.all, w.subnets)
+        }
+    }
+    if b := res.ServiceProjects[1].Subnets; len(b) == 1 && (b[0].Subnetwork != "web" || len(b[0].Members) != 1) {
+        t.Errorf("svc-b subnets = %+v", b)
+    }
+
+    if len(res.Unattributed) != 2 {
+        t.Fatalf("got %d unattributed bindings, want 2: %+v", len(res.Unattributed), res.Unattributed)
+    }
+    if u := res.Unattributed[0]; u.Subnetwork != "" || u.Member != "group:network-admins@example.com" {
+        t.Errorf("unattributed project binding = %+v", u)
+    }
+    if u := res.Unattributed[1]; u.Subnetwork != "web" || u.Member != "user:alice@example.com" {
+        t.Errorf("unattributed subnet binding = %+v", u)
+    }
+}
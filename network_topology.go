@@ -0,0 +1,271 @@
+package metricsexporter
+/**
+ * VPC network topology graph:
+ * networks -> subnets -> instances / GKE clusters, networks -> routers -> NAT
+ * and interconnect attachments, and networks <-> peered networks.
+ *
+ * The graph is returned as json (nodes and edges) or as Graphviz DOT.
+ *
+ * @usage
+ * {"resource": "network", "target": "topology", "arg1": "dot", ...} | dot -Tsvg > topology.svg
+ **/
+
+import (
+    "bytes"
+    "encoding/json"
+    "fmt"
+    "strings"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+const (
+    COMPUTE_API_PREFIX = "https://www.googleapis.com/compute/v1/"
+)
+
+/* Node of the topology graph.
+ */
+type topologyNode struct {
+    Id     string  `json:"id"`
+    Type   string  `json:"type"`
+    Label  string  `json:"label"`
+}
+
+/* Edge of the topology graph.
+ */
+type topologyEdge struct {
+    From  string  `json:"from"`
+    To    string  `json:"to"`
+    Type  string  `json:"type"`
+}
+
+/* Topology graph.
+ */
+type topologyGraph struct {
+    Nodes      []topologyNode         `json:"nodes"`
+    Edges      []topologyEdge         `json:"edges"`
+    seen       map[string]bool
+    seenEdges  map[topologyEdge]bool
+}
+
+/* getTopology builds the topology graph of the project. Set Arg1 to
+ * "dot" to get Graphviz DOT instead of json.
+ */
+func (n *Network) getTopology(format string) (string, error) {
+    networks, err := n.listAllNetworks()
+    if err != nil {
+        return fmt.Sprintf("failed to list networks: "), err
+    }
+    var subnets []*compute.Subnetwork
+    err = n.client.Subnetworks.AggregatedList(n.Project).Pages(n.context, func(list *compute.SubnetworkAggregatedList) error {
+        for _, scoped := range list.Items {
+            subnets = append(subnets, scoped.Subnetworks...)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list subnetworks: "), err
+    }
+    instances, err := n.listAllInstances()
+    if err != nil {
+        return fmt.Sprintf("failed to list instances: "), err
+    }
+    var routers []*compute.Router
+    err = n.client.Routers.AggregatedList(n.Project).Pages(n.context, func(list *compute.RouterAggregatedList) error {
+        for _, scoped := range list.Items {
+            routers = append(routers, scoped.Routers...)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list routers: "), err
+    }
+    var attachments []*compute.InterconnectAttachment
+    err = n.client.InterconnectAttachments.AggregatedList(n.Project).Pages(n.context, func(list *compute.InterconnectAttachmentAggregatedList) error {
+        for _, scoped := range list.Items {
+            attachments = append(attachments, scoped.InterconnectAttachments...)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list interconnect attachments: "), err
+    }
+
+    g := newTopologyGraph(networks, subnets, instances, routers, attachments)
+    if format == "dot" {
+        return g.dot(), nil
+    }
+
+    json, err := json.MarshalIndent(g, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* listAllNetworks lists every VPC network of the project.
+ */
+func (n *Network) listAllNetworks() ([]*compute.Network, error) {
+    var res []*compute.Network
+    err := n.client.Networks.List(n.Project).Pages(n.context, func(list *compute.NetworkList) error {
+        res = append(res, list.Items...)
+        return nil
+    })
+    return res, err
+}
+
+/* newTopologyGraph links the resources together by their self links.
+ * GKE nodes are grouped into a single node per cluster, identified by
+ * its project, location and name.
+ */
+func newTopologyGraph(networks []*compute.Network, subnets []*compute.Subnetwork, instances []*compute.Instance,
+    routers []*compute.Router, attachments []*compute.InterconnectAttachment) *topologyGraph {
+    g := &topologyGraph{
+        Nodes:     []topologyNode{},
+        Edges:     []topologyEdge{},
+        seen:      map[string]bool{},
+        seenEdges: map[topologyEdge]bool{},
+    }
+
+    for _, nw := range networks {
+        g.addNode(nw.SelfLink, "network")
+        for _, p := range nw.Peerings {
+            g.addNode(p.Network, "network")
+            g.addEdge(nw.SelfLink, p.Network, "peering")
+        }
+    }
+    for _, s := range subnets {
+        g.addNode(s.SelfLink, "subnet")
+        g.addEdge(s.Network, s.SelfLink, "contains")
+    }
+    for _, inst := range instances {
+        id, kind := inst.SelfLink, "instance"
+        if cluster := instanceMetadata(inst, "cluster-name"); cluster != "" {
+            id, kind = gkeClusterId(inst, cluster), "gke"
+        }
+        g.addNode(id, kind)
+        for _, nic := range inst.NetworkInterfaces {
+            g.addEdge(nic.Subnetwork, id, "contains")
+        }
+    }
+    for _, r := range routers {
+        g.addNode(r.SelfLink, "router")
+        g.addEdge(r.Network, r.SelfLink, "contains")
+        for _, nat := range r.Nats {
+            id := r.SelfLink + "/nats/" + nat.Name
+            g.addNode(id, "nat")
+            g.addEdge(r.SelfLink, id, "nat")
+        }
+    }
+    for _, a := range attachments {
+        g.addNode(a.SelfLink, "interconnectAttachment")
+        g.addEdge(a.Router, a.SelfLink, "attachment")
+        if a.Interconnect != "" {
+            g.addNode(a.Interconnect, "interconnect")
+            g.addEdge(a.SelfLink, a.Interconnect, "interconnect")
+        }
+    }
+
+    return g
+}
+
+/* addNode adds a node for the resource at link, once.
+ */
+func (g *topologyGraph) addNode(link, kind string) {
+    id := strings.TrimPrefix(link, COMPUTE_API_PREFIX)
+    if g.seen[id] {
+        return
+    }
+    g.seen[id] = true
+    g.Nodes = append(g.Nodes, topologyNode{Id: id, Type: kind, Label: lastPathElement(id)})
+}
+
+/* addEdge links the resources at from and to, once.
+ */
+func (g *topologyGraph) addEdge(from, to, kind string) {
+    if from == "" || to == "" {
+        return
+    }
+    e := topologyEdge{
+        From: strings.TrimPrefix(from, COMPUTE_API_PREFIX),
+        To:   strings.TrimPrefix(to, COMPUTE_API_PREFIX),
+        Type: kind,
+    }
+    if g.seenEdges[e] {
+        return
+    }
+    g.seenEdges[e] = true
+    g.Edges = append(g.Edges, e)
+}
+
+/* gkeClusterId returns the id of the GKE cluster of node inst, eg-
+ * "projects/my-project/locations/us-central1/clusters/my-cluster". Nodes
+ * have the cluster location in their metadata, else the region of their zone is used.
+ */
+func gkeClusterId(inst *compute.Instance, cluster string) string {
+    project, _ := networkProjectAndName(inst.SelfLink)
+    location := instanceMetadata(inst, "cluster-location")
+    if location == "" {
+        location = zoneRegion(lastPathElement(inst.Zone))
+    }
+    return fmt.Sprintf("projects/%s/locations/%s/clusters/%s", project, location, cluster)
+}
+
+/* zoneRegion returns the region of zone, eg- "us-central1" for "us-central1-a".
+ */
+func zoneRegion(zone string) string {
+    if i := strings.LastIndex(zone, "-"); i > 0 {
+        return zone[:i]
+    }
+    return zone
+}
+
+/* dot renders the graph in the Graphviz DOT language.
+ */
+func (g *topologyGraph) dot() string {
+    shapes := map[string]string{
+        "network":                "hexagon",
+        "subnet":                 "box",
+        "instance":               "ellipse",
+        "gke":                    "component",
+        "router":                 "diamond",
+        "nat":                    "invtriangle",
+        "interconnectAttachment": "cds",
+        "interconnect":           "doubleoctagon",
+    }
+
+    var b bytes.Buffer
+    b.WriteString("digraph topology {\n")
+    for _, node := range g.Nodes {
+        fmt.Fprintf(&b, "\t%q [label=%q, shape=%s];\n", node.Id, node.Type + "\n" + node.Label, shapes[node.Type])
+    }
+    for _, e := range g.Edges {
+        style := "solid"
+        if e.Type == "peering" {
+            style = "dashed"
+        }
+        fmt.Fprintf(&b, "\t%q -> %q [label=%q, style=%s];\n", e.From, e.To, e.Type, style)
+    }
+    b.WriteString("}\n")
+    return b.String()
+}
+
+/* instanceMetadata returns the metadata value of key on inst.
+ */
+func instanceMetadata(inst *compute.Instance, key string) string {
+    return metadataValue(inst.Metadata, key)
+}
+
+/* metadataValue returns the value of key in the instance or project metadata md.
+ */
+func metadataValue(md *compute.Metadata, key string) string {
+    if md == nil {
+        return ""
+    }
+    for _, item := range md.Items {
+        if item.Key == key && item.Value != nil {
+            return *item.Value
+        }
+    }
+    return ""
+}
@@ -0,0 +1,53 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func gkeNode(name, zone, cluster, location string) *compute.Instance {
+    items := []*compute.MetadataItems{{Key: "cluster-name", Value: &cluster}}
+    if location != "" {
+        items = append(items, &compute.MetadataItems{Key: "cluster-location", Value: &location})
+    }
+    return &compute.Instance{
+        Name:     name,
+        Zone:     COMPUTE_API_PREFIX + "projects/p/zones/" + zone,
+        SelfLink: COMPUTE_API_PREFIX + "projects/p/zones/" + zone + "/instances/" + name,
+        Metadata: &compute.Metadata{Items: items},
+        NetworkInterfaces: []*compute.NetworkInterface{
+            {Subnetwork: COMPUTE_API_PREFIX + "projects/p/regions/us-central1/subnetworks/default"},
+        },
+    }
+}
+
+func TestNewTopologyGraph(t *testing.T) {
+    instances := []*compute.Instance{
+        gkeNode("node-1", "us-central1-a", "web", "us-central1"),
+        gkeNode("node-2", "us-central1-b", "web", "us-central1"),
+        gkeNode("node-3", "us-central1-a", "web", "us-central1-a"),
+        gkeNode("node-4", "europe-west1-b", "web", ""),
+    }
+    g := newTopologyGraph(nil, nil, instances, nil, nil)
+
+    // Clusters with the same name in different locations are different nodes.
+    wantNodes := map[string]bool{
+        "projects/p/locations/us-central1/clusters/web":   true,
+        "projects/p/locations/us-central1-a/clusters/web": true,
+        "projects/p/locations/europe-west1/clusters/web":  true,
+    }
+    if len(g.Nodes) != len(wantNodes) {
+        t.Errorf("got %d nodes, want %d: %+v", len(g.Nodes), len(wantNodes), g.Nodes)
+    }
+    for _, n := range g.Nodes {
+        if !wantNodes[n.Id] || n.Type != "gke" || n.Label != "web" {
+            t.Errorf("unexpected node %+v", n)
+        }
+    }
+
+    // The nodes of a cluster in the same subnet are linked once.
+    if len(g.Edges) != 3 {
+        t.Errorf("got %d edges, want 3: %+v", len(g.Edges), g.Edges)
+    }
+}
@@ -0,0 +1,307 @@
+package metricsexporter
+/**
+ * Unused (forgotten) network resources we still pay for.
+ *
+ * @see https://cloud.google.com/vpc/network-pricing
+ **/
+
+import (
+    "encoding/json"
+    "fmt"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+/* getWaste finds the reserved external addresses not in use, the
+ * forwarding rules that send traffic to no backend, the Cloud NAT
+ * gateways no VM uses and the VPN tunnels that are not established.
+ * Costs come from the same price table as the compute waste target.
+ */
+func (n *Network) getWaste() (string, error) {
+    report := newWasteReport()
+    prices, err := loadPriceTable()
+    if err != nil {
+        return fmt.Sprintf("failed to load price catalog: "), err
+    }
+
+    // Static external addresses are billed when they are not used.
+    err = n.client.Addresses.AggregatedList(n.Project).Pages(n.context, func(list *compute.AddressAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, a := range scoped.Addresses {
+                if a.Status == "RESERVED" && a.AddressType != "INTERNAL" {
+                    region := lastPathElement(a.Region)
+                    report.add("address", a.Name, region, a.Address, prices.StaticIpMonth * prices.regionMultiplier(region))
+                }
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list addresses: "), err
+    }
+    err = n.client.GlobalAddresses.List(n.Project).Pages(n.context, func(list *compute.AddressList) error {
+        for _, a := range list.Items {
+            if a.Status == "RESERVED" && a.AddressType != "INTERNAL" {
+                report.add("address", a.Name, SCOPE_GLOBAL, a.Address, prices.StaticIpMonth * prices.regionMultiplier(SCOPE_GLOBAL))
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list global addresses: "), err
+    }
+
+    refs, empty, err := n.listLoadBalancingTargets()
+    if err != nil {
+        return fmt.Sprintf("failed to list load balancing targets: "), err
+    }
+    seen := map[string]bool{}
+    addRule := func(r *compute.ForwardingRule) {
+        if seen[r.SelfLink] {
+            return
+        }
+        seen[r.SelfLink] = true
+        target := r.Target
+        if r.BackendService != "" {
+            target = r.BackendService
+        }
+        if target == "" || !lbTargetUnused(target, refs, empty, 0) {
+            return
+        }
+        location := SCOPE_GLOBAL
+        if r.Region != "" {
+            location = lastPathElement(r.Region)
+        }
+        report.add("forwardingrule", r.Name, location, "no backend behind " + lastPathElement(target),
+            prices.ForwardingRuleHour * HOURS_PER_MONTH * prices.regionMultiplier(location))
+    }
+    err = n.client.GlobalForwardingRules.List(n.Project).Pages(n.context, func(list *compute.ForwardingRuleList) error {
+        for _, r := range list.Items {
+            addRule(r)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list global forwarding rules: "), err
+    }
+    err = n.client.ForwardingRules.AggregatedList(n.Project).Pages(n.context, func(list *compute.ForwardingRuleAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, r := range scoped.ForwardingRules {
+                addRule(r)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list forwarding rules: "), err
+    }
+
+    // An idle NAT gateway still holds its external addresses.
+    var routers []*compute.Router
+    err = n.client.Routers.AggregatedList(n.Project).Pages(n.context, func(list *compute.RouterAggregatedList) error {
+        for _, scoped := range list.Items {
+            routers = append(routers, scoped.Routers...)
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list routers: "), err
+    }
+    for _, r := range routers {
+        if len(r.Nats) == 0 {
+            continue
+        }
+        region := lastPathElement(r.Region)
+        status, err := n.client.Routers.GetRouterStatus(n.Project, region, r.Name).Context(n.context).Do()
+        if err != nil {
+            return fmt.Sprintf("failed to get router status: "), err
+        }
+        if status.Result == nil {
+            continue
+        }
+        for _, nat := range status.Result.NatStatus {
+            if nat.NumVmEndpointsWithNatMappings > 0 {
+                continue
+            }
+            ips := len(nat.UserAllocatedNatIps) + len(nat.AutoAllocatedNatIps)
+            report.add("nat", r.Name + "/" + nat.Name, region, fmt.Sprintf("no VM uses its %d addresses", ips),
+                float64(ips) * prices.ExternalIpHour * HOURS_PER_MONTH * prices.regionMultiplier(region))
+        }
+    }
+
+    // Tunnels are billed by the hour, whether they carry traffic or not.
+    err = n.client.VpnTunnels.AggregatedList(n.Project).Pages(n.context, func(list *compute.VpnTunnelAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, t := range scoped.VpnTunnels {
+                if t.Status == "ESTABLISHED" {
+                    continue
+                }
+                region := lastPathElement(t.Region)
+                report.add("vpntunnel", t.Name, region, t.Status + ": " + t.DetailedStatus,
+                    prices.VpnTunnelHour * HOURS_PER_MONTH * prices.regionMultiplier(region))
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return fmt.Sprintf("failed to list vpn tunnels: "), err
+    }
+
+    if err := n.emitWaste(report); err != nil {
+        return fmt.Sprintf("failed to emit waste metrics: "), err
+    }
+
+    json, err := json.MarshalIndent(report, "", "\t")
+    if err != nil {
+        return "", err
+    }
+    return fmt.Sprintf("%s", json), nil
+}
+
+/* listLoadBalancingTargets returns the links every target proxy and url
+ * map forwards to, and the backend services and target pools without
+ * backends.
+ */
+func (n *Network) listLoadBalancingTargets() (map[string][]string, map[string]bool, error) {
+    refs := map[string][]string{}
+    empty := map[string]bool{}
+
+    services, err := n.listAllBackendServices()
+    if err != nil {
+        return nil, nil, err
+    }
+    for _, s := range services {
+        empty[s.SelfLink] = len(s.Backends) == 0
+    }
+    err = n.client.TargetPools.AggregatedList(n.Project).Pages(n.context, func(list *compute.TargetPoolAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, p := range scoped.TargetPools {
+                empty[p.SelfLink] = len(p.Instances) == 0
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+    err = n.client.UrlMaps.AggregatedList(n.Project).Pages(n.context, func(list *compute.UrlMapsAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, m := range scoped.UrlMaps {
+                refs[m.SelfLink] = urlMapServices(m)
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+    err = n.client.TargetHttpProxies.AggregatedList(n.Project).Pages(n.context, func(list *compute.TargetHttpProxyAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, p := range scoped.TargetHttpProxies {
+                refs[p.SelfLink] = []string{p.UrlMap}
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+    err = n.client.TargetHttpsProxies.AggregatedList(n.Project).Pages(n.context, func(list *compute.TargetHttpsProxyAggregatedList) error {
+        for _, scoped := range list.Items {
+            for _, p := range scoped.TargetHttpsProxies {
+                refs[p.SelfLink] = []string{p.UrlMap}
+            }
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+    err = n.client.TargetGrpcProxies.List(n.Project).Pages(n.context, func(list *compute.TargetGrpcProxyList) error {
+        for _, p := range list.Items {
+            refs[p.SelfLink] = []string{p.UrlMap}
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+    err = n.client.TargetSslProxies.List(n.Project).Pages(n.context, func(list *compute.TargetSslProxyList) error {
+        for _, p := range list.Items {
+            refs[p.SelfLink] = []string{p.Service}
+        }
+        return nil
+    })
+    if err != nil {
+        return nil, nil, err
+    }
+    err = n.client.TargetTcpProxies.List(n.Project).Pages(n.context, func(list *compute.TargetTcpProxyList) error {
+        for _, p := range list.Items {
+            refs[p.SelfLink] = []string{p.Service}
+        }
+        return nil
+    })
+    return refs, empty, err
+}
+
+/* urlMapServices returns the services a url map routes to. Redirects are
+ * returned as "redirect", which is never unused.
+ */
+func urlMapServices(m *compute.UrlMap) []string {
+    var res []string
+    add := func(service string, action *compute.HttpRouteAction, redirect *compute.HttpRedirectAction) {
+        if service != "" {
+            res = append(res, service)
+        }
+        if action != nil {
+            for _, w := range action.WeightedBackendServices {
+                res = append(res, w.BackendService)
+            }
+        }
+        if redirect != nil {
+            res = append(res, "redirect")
+        }
+    }
+    add(m.DefaultService, m.DefaultRouteAction, m.DefaultUrlRedirect)
+    for _, pm := range m.PathMatchers {
+        add(pm.DefaultService, pm.DefaultRouteAction, pm.DefaultUrlRedirect)
+        for _, r := range pm.PathRules {
+            add(r.Service, r.RouteAction, r.UrlRedirect)
+        }
+        for _, r := range pm.RouteRules {
+            add(r.Service, r.RouteAction, r.UrlRedirect)
+        }
+    }
+    return res
+}
+
+/* lbTargetUnused tells whether target sends traffic to no backend: it is
+ * a backend service or target pool without backends, or a proxy or url
+ * map whose targets are all unused. Unknown targets (backend buckets,
+ * target instances, ...) are used.
+ */
+func lbTargetUnused(target string, refs map[string][]string, empty map[string]bool, depth int) bool {
+    if e, ok := empty[target]; ok {
+        return e
+    }
+    next, ok := refs[target]
+    if !ok || len(next) == 0 || depth > 4 {
+        return false
+    }
+    for _, t := range next {
+        if !lbTargetUnused(t, refs, empty, depth + 1) {
+            return false
+        }
+    }
+    return true
+}
+
+/* emitWaste sends the number and estimated monthly cost of the unused
+ * network resources of every kind as metrics.
+ */
+func (n *Network) emitWaste(report wasteReport) error {
+    if !n.EnableEmitter {
+        return nil
+    }
+    return emitCollectors(n.emitter, "network.waste", n.Project, newWasteCollectors(n.Project, report, "address", "forwardingrule", "nat", "vpntunnel")...)
+}
@@ -0,0 +1,68 @@
+package metricsexporter
+
+import (
+    "testing"
+
+    compute  "google.golang.org/api/compute/v1"
+)
+
+func TestUrlMapServices(t *testing.T) {
+    m := &compute.UrlMap{
+        DefaultService: "default",
+        PathMatchers: []*compute.PathMatcher{{
+            DefaultService: "matcher",
+            PathRules:      []*compute.PathRule{{Service: "path"}, {UrlRedirect: &compute.HttpRedirectAction{}}},
+            RouteRules: []*compute.HttpRouteRule{{RouteAction: &compute.HttpRouteAction{
+                WeightedBackendServices: []*compute.WeightedBackendService{{BackendService: "weighted"}},
+            }}},
+        }},
+    }
+    got := urlMapServices(m)
+    want := []string{"default", "matcher", "path", "redirect", "weighted"}
+    if len(got) != len(want) {
+        t.Fatalf("urlMapServices() = %v, want %v", got, want)
+    }
+    for i := range want {
+        if got[i] != want[i] {
+            t.Errorf("urlMapServices() = %v, want %v", got, want)
+        }
+    }
+}
+
+func TestLbTargetUnused(t *testing.T) {
+    refs := map[string][]string{
+        "proxy-empty":   {"map-empty"},
+        "proxy-used":    {"map-mixed"},
+        "proxy-bucket":  {"map-bucket"},
+        "proxy-nomap":   {},
+        "map-empty":     {"bs-empty", "bs-empty-2"},
+        "map-mixed":     {"bs-empty", "bs-used"},
+        "map-bucket":    {"bucket"},
+        "map-redirect":  {"redirect"},
+    }
+    empty := map[string]bool{
+        "bs-empty":   true,
+        "bs-empty-2": true,
+        "bs-used":    false,
+        "pool-empty": true,
+    }
+    tests := []struct {
+        target  string
+        want    bool
+    }{
+        {"bs-empty", true},
+        {"bs-used", false},
+        {"pool-empty", true},
+        {"proxy-empty", true},
+        {"proxy-used", false},
+        {"proxy-bucket", false},
+        {"proxy-nomap", false},
+        {"map-redirect", false},
+        {"target-instance", false},
+    }
+    for _, tt := range tests {
+        if got := lbTargetUnused(tt.target, refs, empty, 0); got != tt.want {
+            t.Errorf("lbTargetUnused(%s) = %v, want %v", tt.target, got, tt.want)
+        }
+    }
+}
@@ -6,6 +6,11 @@ type Plugins interface {
     Do(string)  (string, error)
 }
 
+const (
+    // Location of the global (non regional, non zonal) resources.
+    SCOPE_GLOBAL = "global"
+)
+
 /* Common GCP metadata we require for all projects.
  */
 type GcpMetadata struct {
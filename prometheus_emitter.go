@@ -48,6 +48,13 @@ func (p *PrometheusPush) Collector(c prometheus.Collector) {
     p.pusher.Collector(c)
 }
 
+/* Grouping is just a facade around push.Grouping().
+ * See https://godoc.org/github.com/prometheus/client_golang/prometheus/push#Pusher.Grouping
+ */
+func (p *PrometheusPush) Grouping(name, value string) {
+    p.pusher.Grouping(name, value)
+}
+
 /* Emit sends the metric to the Prometheus PushGateway.
  * It is just a wrapper around push.Push().
  * See https://godoc.org/github.com/prometheus/client_golang/prometheus/push#Pusher.Push
@@ -55,3 +62,45 @@ func (p *PrometheusPush) Collector(c prometheus.Collector) {
 func (p *PrometheusPush) Emit() error {
     return p.pusher.Push()
 }
+
+/* newGaugeVec creates an unregistered gauge vector. A fresh vector
+ * should be created for every request so that stale label values
+ * are not pushed again.
+ */
+func newGaugeVec(name, help string, labels ...string) *prometheus.GaugeVec {
+    return prometheus.NewGaugeVec(prometheus.GaugeOpts{
+        Name: name,
+        Help: help,
+    }, labels)
+}
+
+/* emitCollectors attaches the collectors to the emitter and sends them.
+ * It does nothing if the emitter was not enabled in the builder.
+ *
+ * Push replaces every metric of its grouping key, so the metrics are
+ * grouped by target (prefixed with the resource, eg- "gke.operations.list")
+ * and project: each request only replaces its own metrics. The project
+ * grouping label is gcp_project because the metrics already have a project
+ * label, which the PushGateway does not accept in the grouping key.
+ */
+func emitCollectors(e Emitters, target, project string, cs ...prometheus.Collector) error {
+    p, ok := e.(*PrometheusPush)
+    if !ok || p == nil {
+        return nil
+    }
+    p.Grouping("target", target)
+    p.Grouping("gcp_project", project)
+    for _, c := range cs {
+        p.Collector(c)
+    }
+    return p.Emit()
+}
+
+/* boolToFloat returns 1 for true and 0 for false, as gauges expect.
+ */
+func boolToFloat(b bool) float64 {
+    if b {
+        return 1
+    }
+    return 0
+}
@@ -0,0 +1,39 @@
+package metricsexporter
+
+import (
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+)
+
+func TestEmitCollectorsGrouping(t *testing.T) {
+    var method, path string
+    srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        method, path = r.Method, r.URL.Path
+        w.WriteHeader(http.StatusAccepted)
+    }))
+    defer srv.Close()
+
+    gauge := newGaugeVec("test_metric", "Test metric.", "project")
+    gauge.WithLabelValues("my-project").Set(1)
+    if err := emitCollectors(NewPrometheusPush(srv.URL, PROM_PUSHGW_JOB), "compute.waste", "my-project", gauge); err != nil {
+        t.Fatalf("emitCollectors: %v", err)
+    }
+    if method != http.MethodPut {
+        t.Errorf("method = %s, want PUT", method)
+    }
+    // The grouping labels are not sorted.
+    for _, want := range []string{"/metrics/job/" + PROM_PUSHGW_JOB + "/", "/gcp_project/my-project", "/target/compute.waste"} {
+        if !strings.Contains(path, want) {
+            t.Errorf("path = %s, want it to contain %s", path, want)
+        }
+    }
+}
+
+func TestEmitCollectorsDisabled(t *testing.T) {
+    var e Emitters
+    if err := emitCollectors(e, "compute.waste", "my-project", newGaugeVec("test_metric", "Test metric.")); err != nil {
+        t.Errorf("emitCollectors without emitter: %v", err)
+    }
+}
@@ -2,22 +2,41 @@ package metricsexporter
 
 /**/
 type Query struct {
-    Resource   string `json:"resource"`
-    Project    string `json:"project"`
-    Zone       string `json:"zone"`
-    Region     string `json:"region"`
-    Action     string `json:"action"`
-    Namespace  string `json:"namespace"`
-    Target     string `json:"target"`
-    Arg1       string `json:"arg1"`
+    Resource       string `json:"resource"`
+    Project        string `json:"project"`
+    Zone           string `json:"zone"`
+    Region         string `json:"region"`
+    Action         string `json:"action"`
+    Namespace      string `json:"namespace"`
+    Target         string `json:"target"`
+    Arg1           string `json:"arg1"`
+    KubeNamespace  string `json:"kube_namespace"`
+    Selector       string `json:"selector"`
+    Instance       string `json:"instance"`
+    Tags           string `json:"tags"`
+    ServiceAccount string `json:"service_account"`
+    Direction      string `json:"direction"`
+    Protocol       string `json:"protocol"`
+    Port           string `json:"port"`
+    Cidr           string `json:"cidr"`
+    Retention      string `json:"retention"`
+    OperationType  string `json:"operation_type"`
+    Label          string `json:"label"`
+    Emit           bool   `json:"emit"`
 }
 
 const(
     REQUEST_MAX_LEN = 50
     PING_OK         = "ok"
+
+    // A service account email is at most a 30 character account id and
+    // a 30 character project id, and an instance has at most 64 network
+    // tags of 63 characters.
+    SERVICE_ACCOUNT_MAX_LEN = 100
+    TAGS_MAX_LEN            = 64 * 64
 )
 
 var(
     QueryResources  = []string{"gke", "gke_mock", "health", "network", "compute"}
-    QueryActions    = []string{"get", "ping"}
+    QueryActions    = []string{"get", "ping", "cost"}
 )